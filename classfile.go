@@ -0,0 +1,291 @@
+package gava
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Java access flags used by the serialVersionUID computation.
+const (
+	accPublic       = 0x0001
+	accPrivate      = 0x0002
+	accProtected    = 0x0004
+	accStatic       = 0x0008
+	accFinal        = 0x0010
+	accSynchronized = 0x0020
+	accVolatile     = 0x0040
+	accTransient    = 0x0080
+	accNative       = 0x0100
+	accInterface    = 0x0200
+	accAbstract     = 0x0400
+	accStrict       = 0x0800
+	accEnum         = 0x4000
+)
+
+// classFile holds the parts of a compiled Java class the serialVersionUID depends on.
+type classFile struct {
+	name       string
+	super      string
+	access     uint16
+	interfaces []string
+	fields     []classMember
+	methods    []classMember
+}
+
+type classMember struct {
+	access     uint16
+	name       string
+	descriptor string
+	// constant is the ConstantValue of a field, when it has one.
+	constant interface{}
+}
+
+// ClassFileSUID returns the name and serialVersionUID of the compiled Java class in data,
+// the contents of a .class file. The declared serialVersionUID is returned when the class
+// has one, otherwise the default one Java computes from the class name, modifiers,
+// interfaces and members. Enums, and records without a declared one, have 0.
+func ClassFileSUID(data []byte) (className string, suid int64, err error) {
+	cf, err := parseClassFile(data)
+	if err != nil {
+		return "", 0, err
+	}
+	className = strings.Replace(cf.name, "/", ".", -1)
+	for _, f := range cf.fields {
+		if f.name == "serialVersionUID" && f.access&(accStatic|accFinal) == accStatic|accFinal && f.descriptor == "J" {
+			v, ok := f.constant.(int64)
+			if !ok {
+				return "", 0, fmt.Errorf("gava: %s: serialVersionUID is not a constant", className)
+			}
+			return className, v, nil
+		}
+	}
+	if (cf.access&accEnum != 0 && cf.super == "java/lang/Enum") || cf.super == "java/lang/Record" {
+		return className, 0, nil
+	}
+	return className, cf.defaultSUID(), nil
+}
+
+// defaultSUID computes the serialVersionUID the way java.io.ObjectStreamClass does for a
+// class that doesn't declare one.
+func (cf *classFile) defaultSUID() int64 {
+	var buf bytes.Buffer
+	writeUTF := func(s string) {
+		b := encodeModifiedUTF8(s)
+		binary.Write(&buf, binary.BigEndian, uint16(len(b)))
+		buf.Write(b)
+	}
+	writeInt := func(v int) {
+		binary.Write(&buf, binary.BigEndian, int32(v))
+	}
+
+	var constructors, methods []classMember
+	hasClinit := false
+	for _, m := range cf.methods {
+		switch m.name {
+		case "<clinit>":
+			hasClinit = true
+		case "<init>":
+			constructors = append(constructors, m)
+		default:
+			methods = append(methods, m)
+		}
+	}
+
+	writeUTF(strings.Replace(cf.name, "/", ".", -1))
+	classAccess := int(cf.access) & (accPublic | accFinal | accInterface | accAbstract)
+	if classAccess&accInterface != 0 {
+		if len(methods) > 0 {
+			classAccess |= accAbstract
+		} else {
+			classAccess &^= accAbstract
+		}
+	}
+	writeInt(classAccess)
+
+	interfaces := make([]string, len(cf.interfaces))
+	for i, name := range cf.interfaces {
+		interfaces[i] = strings.Replace(name, "/", ".", -1)
+	}
+	sort.Strings(interfaces)
+	for _, name := range interfaces {
+		writeUTF(name)
+	}
+
+	fields := append([]classMember(nil), cf.fields...)
+	sort.SliceStable(fields, func(i, j int) bool { return fields[i].name < fields[j].name })
+	for _, f := range fields {
+		access := int(f.access) & (accPublic | accPrivate | accProtected | accStatic | accFinal | accVolatile | accTransient)
+		if access&accPrivate == 0 || access&(accStatic|accTransient) == 0 {
+			writeUTF(f.name)
+			writeInt(access)
+			writeUTF(f.descriptor)
+		}
+	}
+
+	if hasClinit {
+		writeUTF("<clinit>")
+		writeInt(accStatic)
+		writeUTF("()V")
+	}
+
+	const methodAccess = accPublic | accPrivate | accProtected | accStatic | accFinal | accSynchronized |
+		accNative | accAbstract | accStrict
+	sort.SliceStable(constructors, func(i, j int) bool { return constructors[i].descriptor < constructors[j].descriptor })
+	for _, m := range constructors {
+		if access := int(m.access) & methodAccess; access&accPrivate == 0 {
+			writeUTF("<init>")
+			writeInt(access)
+			writeUTF(strings.Replace(m.descriptor, "/", ".", -1))
+		}
+	}
+	sort.SliceStable(methods, func(i, j int) bool {
+		if methods[i].name != methods[j].name {
+			return methods[i].name < methods[j].name
+		}
+		return methods[i].descriptor < methods[j].descriptor
+	})
+	for _, m := range methods {
+		if access := int(m.access) & methodAccess; access&accPrivate == 0 {
+			writeUTF(m.name)
+			writeInt(access)
+			writeUTF(strings.Replace(m.descriptor, "/", ".", -1))
+		}
+	}
+
+	//The first 8 bytes of the hash, little endian
+	sum := sha1.Sum(buf.Bytes())
+	return int64(binary.LittleEndian.Uint64(sum[:8]))
+}
+
+var errClassFile = errors.New("gava: invalid class file")
+
+// parseClassFile reads the constant pool, flags, interfaces, fields and methods of a class
+// file, and the InnerClasses attribute for the modifiers of nested classes.
+func parseClassFile(data []byte) (cf *classFile, err error) {
+	defer func() {
+		//Reads past the end of a truncated class file panic with an index out of range
+		if r := recover(); r != nil {
+			cf, err = nil, errClassFile
+		}
+	}()
+	r := &classReader{data: data}
+	if r.u4() != 0xcafebabe {
+		return nil, errClassFile
+	}
+	r.u2() //minor version
+	r.u2() //major version
+
+	count := int(r.u2())
+	pool := make([]interface{}, count)
+	classes := map[int]int{}
+	for i := 1; i < count; i++ {
+		switch tag := r.u1(); tag {
+		case 1: //Utf8
+			pool[i] = decodeModifiedUTF8(r.bytes(int(r.u2())))
+		case 3: //Integer
+			pool[i] = int32(r.u4())
+		case 4: //Float
+			r.u4()
+		case 5: //Long
+			pool[i] = int64(binary.BigEndian.Uint64(r.bytes(8)))
+			i++
+		case 6: //Double
+			r.bytes(8)
+			i++
+		case 7: //Class
+			classes[i] = int(r.u2())
+		case 8, 16, 19, 20: //String, MethodType, Module, Package
+			r.u2()
+		case 9, 10, 11, 12, 17, 18: //Fieldref, Methodref, InterfaceMethodref, NameAndType, Dynamic, InvokeDynamic
+			r.u4()
+		case 15: //MethodHandle
+			r.u1()
+			r.u2()
+		default:
+			return nil, fmt.Errorf("gava: invalid class file: constant pool tag %d", tag)
+		}
+	}
+	utf8 := func(index uint16) string {
+		s, ok := pool[index].(string)
+		if !ok {
+			panic(errClassFile)
+		}
+		return s
+	}
+	class := func(index uint16) string {
+		if index == 0 {
+			return ""
+		}
+		return utf8(uint16(classes[int(index)]))
+	}
+
+	cf = &classFile{access: r.u2()}
+	this := r.u2()
+	cf.name = class(this)
+	cf.super = class(r.u2())
+	for n := r.u2(); n > 0; n-- {
+		cf.interfaces = append(cf.interfaces, class(r.u2()))
+	}
+	members := func() []classMember {
+		var members []classMember
+		for n := r.u2(); n > 0; n-- {
+			m := classMember{access: r.u2(), name: utf8(r.u2()), descriptor: utf8(r.u2())}
+			for a := r.u2(); a > 0; a-- {
+				name := utf8(r.u2())
+				attr := r.bytes(int(r.u4()))
+				if name == "ConstantValue" && len(attr) == 2 {
+					m.constant = pool[binary.BigEndian.Uint16(attr)]
+				}
+			}
+			members = append(members, m)
+		}
+		return members
+	}
+	cf.fields = members()
+	cf.methods = members()
+	for a := r.u2(); a > 0; a-- {
+		name := utf8(r.u2())
+		attr := &classReader{data: r.bytes(int(r.u4()))}
+		if name != "InnerClasses" {
+			continue
+		}
+		//Nested classes take their modifiers from their own entry
+		for n := attr.u2(); n > 0; n-- {
+			inner := attr.u2()
+			attr.u2() //outer class
+			attr.u2() //inner name
+			access := attr.u2()
+			if inner != 0 && class(inner) == cf.name {
+				cf.access = access
+			}
+		}
+	}
+	return cf, nil
+}
+
+type classReader struct {
+	data []byte
+}
+
+func (r *classReader) bytes(n int) []byte {
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+func (r *classReader) u1() byte {
+	return r.bytes(1)[0]
+}
+
+func (r *classReader) u2() uint16 {
+	return binary.BigEndian.Uint16(r.bytes(2))
+}
+
+func (r *classReader) u4() uint32 {
+	return binary.BigEndian.Uint32(r.bytes(4))
+}
@@ -0,0 +1,289 @@
+// Command gava rewrites Java serialization streams.
+//
+// Usage:
+//
+//	gava rename [-class old=new]... [-package old=new]... [-o output] [input]
+//	gava suid [-set class=suid]... [-class file.class]... [-o output] [input]
+//	gava sanitize [-allow pattern]... [-deny pattern]... [-o output] [input]
+//	gava redact [-key key] [-field class.field]... [-o output] [input]
+//	gava scan [-rules file]... [input]
+//	gava validate [input]
+//
+// The input is read from standard input when no file is given, and the output written to
+// standard output without -o. Scan prints the gadget chains it finds and validate the
+// violations of the stream protocol, both exit with status 1 when there are any.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/maPaydar/gava-deserializer"
+)
+
+type command struct {
+	name  string
+	usage string
+	run   func(args []string) error
+}
+
+var commands = []command{
+	{"rename", "[-class old=new]... [-package old=new]... [-o output] [input]", rename},
+	{"suid", "[-set class=suid]... [-class file.class]... [-o output] [input]", suid},
+	{"sanitize", "[-allow pattern]... [-deny pattern]... [-o output] [input]", sanitize},
+	{"redact", "[-key key] [-field class.field]... [-o output] [input]", redact},
+	{"scan", "[-rules file]... [input]", scan},
+	{"validate", "[input]", validate},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	for _, c := range commands {
+		if c.name == os.Args[1] {
+			if err := c.run(os.Args[2:]); err != nil {
+				fmt.Fprintln(os.Stderr, "gava "+c.name+":", err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+	usage()
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage:")
+	for _, c := range commands {
+		fmt.Fprintln(os.Stderr, "  gava "+c.name+" "+c.usage)
+	}
+	os.Exit(2)
+}
+
+// mappings collects repeated old=new flags.
+type mappings map[string]string
+
+func (m mappings) String() string {
+	var s []string
+	for from, to := range m {
+		s = append(s, from+"="+to)
+	}
+	return strings.Join(s, ",")
+}
+
+func (m mappings) Set(v string) error {
+	i := strings.IndexByte(v, '=')
+	if i <= 0 || i == len(v)-1 {
+		return fmt.Errorf("%q is not old=new", v)
+	}
+	m[v[:i]] = v[i+1:]
+	return nil
+}
+
+// readInput reads the file named by the only argument, or standard input.
+func readInput(fs *flag.FlagSet) ([]byte, error) {
+	switch fs.NArg() {
+	case 0:
+		return ioutil.ReadAll(os.Stdin)
+	case 1:
+		return ioutil.ReadFile(fs.Arg(0))
+	}
+	return nil, fmt.Errorf("too many arguments")
+}
+
+func writeOutput(path string, data []byte) error {
+	if path == "" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+func rename(args []string) error {
+	r := &gava.ClassRenamer{Classes: mappings{}, Packages: mappings{}}
+	fs := flag.NewFlagSet("rename", flag.ExitOnError)
+	fs.Var(mappings(r.Classes), "class", "rename the class `old=new`, with its nested classes")
+	fs.Var(mappings(r.Packages), "package", "move the classes of package `old=new`, with its sub-packages")
+	output := fs.String("o", "", "write the stream to `file`")
+	fs.Parse(args)
+
+	data, err := readInput(fs)
+	if err != nil {
+		return err
+	}
+	doc, err := gava.ParseDocument(data)
+	if err != nil {
+		return err
+	}
+	n := doc.RenameClasses(r)
+	out, err := doc.Bytes()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "renamed %d class descriptors\n", n)
+	return writeOutput(*output, out)
+}
+
+// list collects repeated flags.
+type list []string
+
+func (l *list) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *list) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+func suid(args []string) error {
+	set := mappings{}
+	var classes list
+	fs := flag.NewFlagSet("suid", flag.ExitOnError)
+	fs.Var(set, "set", "set the serialVersionUID of a class, `class=suid`")
+	fs.Var(&classes, "class", "set the serialVersionUID of the class compiled to `file`")
+	output := fs.String("o", "", "write the stream to `file`")
+	fs.Parse(args)
+
+	suids := map[string]int64{}
+	for _, path := range classes {
+		classFile, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		class, n, err := gava.ClassFileSUID(classFile)
+		if err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		suids[class] = n
+	}
+	//Explicit values win over computed ones
+	for class, value := range set {
+		n, err := strconv.ParseInt(value, 0, 64)
+		if err != nil {
+			return fmt.Errorf("invalid serialVersionUID %q for %s", value, class)
+		}
+		suids[class] = n
+	}
+
+	data, err := readInput(fs)
+	if err != nil {
+		return err
+	}
+	out, changes, err := gava.RewriteSUIDs(data, suids)
+	if err != nil {
+		return err
+	}
+	for _, c := range changes {
+		fmt.Fprintf(os.Stderr, "%s: %d -> %d\n", c.ClassName, c.Old, c.New)
+	}
+	fmt.Fprintf(os.Stderr, "rewrote %d class descriptors\n", len(changes))
+	return writeOutput(*output, out)
+}
+
+func sanitize(args []string) error {
+	f := &gava.ClassFilter{}
+	fs := flag.NewFlagSet("sanitize", flag.ExitOnError)
+	fs.Var((*list)(&f.Allow), "allow", "keep only classes matching `pattern`, such as com.acme.** or java.util.*")
+	fs.Var((*list)(&f.Deny), "deny", "remove classes matching `pattern`")
+	output := fs.String("o", "", "write the stream to `file`")
+	fs.Parse(args)
+
+	data, err := readInput(fs)
+	if err != nil {
+		return err
+	}
+	doc, err := gava.ParseDocument(data)
+	if err != nil {
+		return err
+	}
+	n := doc.Sanitize(f)
+	out, err := doc.Bytes()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "removed %d values\n", n)
+	return writeOutput(*output, out)
+}
+
+func redact(args []string) error {
+	r := &gava.Redactor{}
+	fs := flag.NewFlagSet("redact", flag.ExitOnError)
+	key := fs.String("key", "", "derive pseudonyms with the secret `key`")
+	fs.Var((*list)(&r.Fields), "field", "redact the primitive fields matching `class.field`, such as com.acme.**.balance")
+	output := fs.String("o", "", "write the stream to `file`")
+	fs.Parse(args)
+	r.Key = []byte(*key)
+
+	data, err := readInput(fs)
+	if err != nil {
+		return err
+	}
+	doc, err := gava.ParseDocument(data)
+	if err != nil {
+		return err
+	}
+	n := doc.Redact(r)
+	out, err := doc.Bytes()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "redacted %d values\n", n)
+	return writeOutput(*output, out)
+}
+
+func scan(args []string) error {
+	var files list
+	fs := flag.NewFlagSet("scan", flag.ExitOnError)
+	fs.Var(&files, "rules", "add the gadget rules of the JSON `file`")
+	fs.Parse(args)
+
+	scanner := &gava.Scanner{Rules: gava.DefaultGadgetRules()}
+	for _, path := range files {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		rules, err := gava.LoadGadgetRules(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		scanner.Rules = append(scanner.Rules, rules...)
+	}
+
+	data, err := readInput(fs)
+	if err != nil {
+		return err
+	}
+	findings := scanner.Scan(data)
+	for _, f := range findings {
+		fmt.Printf("%s %s at offset %d: %s\n", f.Severity, f.Rule, f.Offsets[len(f.Offsets)-1], strings.Join(f.Path, " -> "))
+	}
+	if len(findings) > 0 {
+		return fmt.Errorf("found %d gadget chains", len(findings))
+	}
+	return nil
+}
+
+func validate(args []string) error {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	fs.Parse(args)
+
+	data, err := readInput(fs)
+	if err != nil {
+		return err
+	}
+	violations := gava.Validate(data)
+	for _, v := range violations {
+		fmt.Println(v)
+	}
+	if len(violations) > 0 {
+		return fmt.Errorf("found %d violations", len(violations))
+	}
+	return nil
+}
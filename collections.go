@@ -0,0 +1,144 @@
+package gava
+
+import (
+	"fmt"
+	"reflect"
+)
+
+func init() {
+	RegisterClassReader("java.util.ArrayList", readArrayList)
+	RegisterClassReader("java.util.HashMap", readHashMap)
+	RegisterClassReader("java.util.Collections$EmptyList", readEmptyList)
+	RegisterClassReader("java.util.Collections$UnmodifiableCollection", readUnmodifiableCollection)
+	RegisterClassReader("java.util.EnumSet$SerializationProxy", readEnumSet)
+	RegisterClassReader("java.util.EnumMap", readEnumMap)
+}
+
+// readArrayList reads the size field followed by the capacity and the elements
+// ArrayList.writeObject puts in the annotation.
+func readArrayList(obj *ClassDetails) (interface{}, error) {
+	size, err := intField(obj, "size")
+	if err != nil {
+		return nil, err
+	}
+	in := NewObjectInput(classPart(obj, "java.util.ArrayList"))
+	if _, err := in.ReadInt(); err != nil {
+		return nil, err
+	}
+	return readElements(in, int(size))
+}
+
+// readHashMap reads the capacity and size HashMap.writeObject puts in the annotation,
+// followed by each key and value. LinkedHashMap is read the same way.
+func readHashMap(obj *ClassDetails) (interface{}, error) {
+	in := NewObjectInput(classPart(obj, "java.util.HashMap"))
+	if _, err := in.ReadInt(); err != nil {
+		return nil, err
+	}
+	size, err := in.ReadInt()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := readElements(in, 2*int(size))
+	if err != nil {
+		return nil, err
+	}
+	return mapFromEntries(entries), nil
+}
+
+// readEnumSet reads the elements array RegularEnumSet and JumboEnumSet are replaced with.
+func readEnumSet(obj *ClassDetails) (interface{}, error) {
+	return sliceField(obj, "elements")
+}
+
+// readEnumMap reads the size EnumMap.writeObject puts in the annotation, followed by each
+// key and value.
+func readEnumMap(obj *ClassDetails) (interface{}, error) {
+	in := NewObjectInput(classPart(obj, "java.util.EnumMap"))
+	size, err := in.ReadInt()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := readElements(in, 2*int(size))
+	if err != nil {
+		return nil, err
+	}
+	return mapFromEntries(entries), nil
+}
+
+func readEmptyList(obj *ClassDetails) (interface{}, error) {
+	return []interface{}{}, nil
+}
+
+// readUnmodifiableCollection unwraps the collection held by the Collections.unmodifiable* views.
+func readUnmodifiableCollection(obj *ClassDetails) (interface{}, error) {
+	c, err := fieldData(obj, "c")
+	if err != nil {
+		return nil, err
+	}
+	return decodedValue(c), nil
+}
+
+func readElements(in *ObjectInput, size int) ([]interface{}, error) {
+	if size < 0 {
+		return nil, fmt.Errorf("invalid size %d", size)
+	}
+	elements := []interface{}{}
+	for i := 0; i < size; i++ {
+		e, err := in.ReadObject()
+		if err != nil {
+			return nil, err
+		}
+		elements = append(elements, decodedValue(e))
+	}
+	return elements, nil
+}
+
+// newMap builds a Go map from the keys and values. It returns nil, leaving the object
+// undecoded, when a key can't be used as a Go map key, such as a decoded list.
+func newMap(keys, values []interface{}) interface{} {
+	entries := make(map[interface{}]interface{}, len(keys))
+	for i, k := range keys {
+		if !hashable(k) {
+			return nil
+		}
+		entries[k] = values[i]
+	}
+	return entries
+}
+
+// hashable reports whether v can be used as a Go map key. Values held by interfaces in
+// arrays and structs must be comparable too.
+func hashable(v interface{}) bool {
+	return v == nil || hashableValue(reflect.ValueOf(v))
+}
+
+func hashableValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Interface:
+		return v.IsNil() || hashableValue(v.Elem())
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if !hashableValue(v.Index(i)) {
+				return false
+			}
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if !hashableValue(v.Field(i)) {
+				return false
+			}
+		}
+	}
+	return v.Type().Comparable()
+}
+
+// mapFromEntries builds a map from alternating keys and values.
+func mapFromEntries(entries []interface{}) interface{} {
+	var keys, values []interface{}
+	for i := 0; i+1 < len(entries); i += 2 {
+		keys = append(keys, entries[i])
+		values = append(values, entries[i+1])
+	}
+	return newMap(keys, values)
+}
@@ -0,0 +1,48 @@
+package gava
+
+func init() {
+	for _, pkg := range []string{"org.apache.commons.collections.map.", "org.apache.commons.collections4.map."} {
+		RegisterClassReader(pkg+"Flat3Map", flat3MapReader(pkg+"Flat3Map"))
+		RegisterClassReader(pkg+"HashedMap", hashedMapReader(pkg+"HashedMap"))
+		RegisterClassReader(pkg+"LinkedMap", hashedMapReader(pkg+"LinkedMap"))
+	}
+}
+
+// flat3MapReader reads the size followed by each key and value written by Flat3Map.writeObject.
+func flat3MapReader(className string) ClassReader {
+	return func(obj *ClassDetails) (interface{}, error) {
+		in := NewObjectInput(classPart(obj, className))
+		size, err := in.ReadInt()
+		if err != nil {
+			return nil, err
+		}
+		entries, err := readElements(in, 2*int(size))
+		if err != nil {
+			return nil, err
+		}
+		return mapFromEntries(entries), nil
+	}
+}
+
+// hashedMapReader reads the layout of AbstractHashedMap.doWriteObject: the load factor,
+// the capacity and the size followed by each key and value.
+func hashedMapReader(className string) ClassReader {
+	return func(obj *ClassDetails) (interface{}, error) {
+		in := NewObjectInput(classPart(obj, className))
+		if _, err := in.ReadFloat(); err != nil {
+			return nil, err
+		}
+		if _, err := in.ReadInt(); err != nil {
+			return nil, err
+		}
+		size, err := in.ReadInt()
+		if err != nil {
+			return nil, err
+		}
+		entries, err := readElements(in, 2*int(size))
+		if err != nil {
+			return nil, err
+		}
+		return mapFromEntries(entries), nil
+	}
+}
@@ -0,0 +1,100 @@
+package gava
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// Document is a whole parsed stream: every top-level content element in order, kept with
+// the details needed to write the stream back to the bytes it was parsed from. Class
+// descriptors, shared references, field type strings, block data segments and class and
+// object annotations are all preserved, so a Document can be changed and written back
+// without disturbing the rest of the stream.
+//
+// Blocks of up to 255 bytes written as TC_BLOCKDATALONG, which ObjectOutputStream never
+// does, are written back as TC_BLOCKDATA.
+type Document struct {
+	// Contents are the top-level content elements: objects, strings, arrays, enums,
+	// classes, class descriptors, block data, nil and Reset. Classes and class descriptors
+	// are both *ClassDataDesc, and are written back the way they were read.
+	Contents []interface{}
+	// ContentSpans are the spans of the elements of Contents when the document was parsed.
+	ContentSpans []Span
+	// Packet is the RMI packet type byte before the stream magic, or 0 when there is none.
+	Packet byte
+	// Nested are the streams found inside byte arrays and block data when decoding with
+	// DecoderOptions.NestedDepth. Changes to them aren't written back by Bytes.
+	Nested []*NestedStream
+	// Diagnostics are the problems found when decoding with DecoderOptions.Lenient.
+	Diagnostics []Diagnostic
+	version     uint16
+	// maxValueLength and noValues are the DecoderOptions.MaxValueLength and
+	// GavaDeserilizer.noValues the document was parsed with
+	maxValueLength int
+	noValues       bool
+}
+
+// ParseDocument parses every content element of the stream in data.
+func ParseDocument(data []byte) (*Document, error) {
+	return NewGavaDeserilizer(data).decodeDocument()
+}
+
+func (g *GavaDeserilizer) decodeDocument() (doc *Document, err error) {
+	defer g.recoverError(&err)
+
+	doc = &Document{maxValueLength: g.opts.MaxValueLength, noValues: g.noValues}
+	header := g.data
+	doc.Packet, _ = g.readHeader()
+	//The version is the last 2 bytes of the header
+	doc.version = binary.BigEndian.Uint16(header[len(header)-len(g.data)-2:])
+	g.lenient = g.opts.Lenient
+	g.readContents(doc)
+	if g.opts.NestedDepth > 0 {
+		doc.Nested = g.opts.nestedStreams(doc)
+	}
+	return doc, nil
+}
+
+// setValue formats f.Value again after f.Data changed. Values longer than the
+// DecoderOptions.MaxValueLength the document was parsed with are left empty.
+func (d *Document) setValue(f *ClassField) {
+	if d.noValues {
+		return
+	}
+	f.Value = valueString(f.Data)
+	if d.maxValueLength > 0 && len(f.Value) > d.maxValueLength {
+		f.Value = ""
+	}
+}
+
+// Object returns the first object of the document, or nil when there is none.
+func (d *Document) Object() *ClassDetails {
+	for _, c := range d.Contents {
+		if obj, ok := c.(*ClassDetails); ok {
+			return obj
+		}
+	}
+	return nil
+}
+
+// Bytes encodes the document. A document that wasn't changed is encoded to the bytes it
+// was parsed from, handles are renumbered to follow the contents when it was.
+func (d *Document) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	if d.Packet != 0 {
+		buf.WriteByte(d.Packet)
+	}
+	version := d.version
+	if version == 0 {
+		version = 5
+	}
+	buf.Write([]byte{0xac, 0xed, byte(version >> 8), byte(version)})
+	e := NewEncoder(&buf)
+	e.headerWritten = true
+	for _, c := range d.Contents {
+		if err := e.Encode(c); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,290 @@
+package gava
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Byte returns v as the value of a Java byte, for Set.
+func Byte(v int8) int8 { return v }
+
+// Char returns v as the value of a Java char, for Set.
+func Char(v uint16) uint16 { return v }
+
+// Short returns v as the value of a Java short, for Set.
+func Short(v int16) int16 { return v }
+
+// Int returns v as the value of a Java int, for Set.
+func Int(v int32) int32 { return v }
+
+// Long returns v as the value of a Java long, for Set.
+func Long(v int64) int64 { return v }
+
+// Float returns v as the value of a Java float, for Set.
+func Float(v float32) float32 { return v }
+
+// Double returns v as the value of a Java double, for Set.
+func Double(v float64) float64 { return v }
+
+// Boolean returns v as the value of a Java boolean, for Set.
+func Boolean(v bool) bool { return v }
+
+// String returns a new Java string object, for Set.
+func String(v string) *JavaString { return &JavaString{Value: v} }
+
+// Get returns the value at path in the first object of the document, see ClassDetails.Get.
+func (d *Document) Get(path string) (interface{}, error) {
+	obj := d.Object()
+	if obj == nil {
+		return nil, errors.New("gava: document has no object")
+	}
+	return obj.Get(path)
+}
+
+// Set replaces the value at path in the first object of the document, see ClassDetails.Set.
+func (d *Document) Set(path string, v interface{}) error {
+	obj := d.Object()
+	if obj == nil {
+		return errors.New("gava: document has no object")
+	}
+	return obj.Set(path, v)
+}
+
+// Get returns the value at path. A path is a list of field names separated by dots, each
+// optionally followed by array indexes, such as "title.value" or "tags[1]". Fields are
+// looked up through the super classes as well.
+func (cd *ClassDetails) Get(path string) (interface{}, error) {
+	s, err := cd.resolve(path)
+	if err != nil {
+		return nil, err
+	}
+	return s.get(), nil
+}
+
+// Set replaces the value at path, see Get for the path syntax. The value must suit the
+// type of the field or array: int32 for an int, int64 for a long and so on, as returned by
+// Int, Long and the other constructors, with Go ints accepted when they fit. Object fields
+// take nil, strings, *JavaString, objects, arrays, enums and classes that can be assigned to
+// the declared type of the field; the hierarchy of object classes isn't in the stream, so
+// objects are only rejected where arrays or the final classes String and Class are declared.
+//
+// Objects shared with other parts of the stream stay shared, only the slot at path changes.
+// Objects on the path are decoded again, so their Decoded value follows the change.
+func (cd *ClassDetails) Set(path string, v interface{}) error {
+	s, err := cd.resolve(path)
+	if err != nil {
+		return err
+	}
+	v, err = fieldValue(s.typeCode(), v)
+	if err != nil {
+		return fmt.Errorf("gava: %s: %v", path, err)
+	}
+	//Java would throw a ClassCastException, or an ArrayStoreException for array elements
+	if typeCode := s.typeCode(); typeCode == 'L' || typeCode == '[' {
+		if signature := s.signature(); signature != "" && !settable(signature, v) {
+			return fmt.Errorf("gava: %s: cannot set %T as %s", path, v, signature)
+		}
+	}
+	s.set(v)
+	for i := len(s.objects) - 1; i >= 0; i-- {
+		if i < len(s.fields) {
+			s.fields[i].Value = valueString(s.fields[i].Data)
+		}
+		//Objects that no longer decode are left undecoded
+		if decode(s.objects[i]) != nil {
+			s.objects[i].Decoded = nil
+		}
+	}
+	return nil
+}
+
+// slot is a field or array element found by a path, with the objects and fields on the
+// way to it.
+type slot struct {
+	objects []*ClassDetails
+	fields  []*ClassField
+	array   *JavaArray
+	index   int
+}
+
+func (s *slot) get() interface{} {
+	if s.array != nil {
+		return s.array.Elements[s.index]
+	}
+	return s.fields[len(s.fields)-1].Data
+}
+
+func (s *slot) set(v interface{}) {
+	if s.array != nil {
+		s.array.Elements[s.index] = v
+		return
+	}
+	s.fields[len(s.fields)-1].Data = v
+}
+
+func (s *slot) typeCode() byte {
+	if s.array != nil {
+		return s.array.ClassName[1]
+	}
+	return s.fields[len(s.fields)-1].TypeCode
+}
+
+// signature returns the declared type of an object or array slot, such as
+// "Ljava/lang/String;", or "" when it isn't known.
+func (s *slot) signature() string {
+	if s.array != nil {
+		return strings.Replace(s.array.ClassName[1:], ".", "/", -1)
+	}
+	return s.fields[len(s.fields)-1].className
+}
+
+// pathSegment is a field name of a path with the array indexes following it.
+type pathSegment struct {
+	name    string
+	indexes []string
+}
+
+func parsePath(path string) ([]pathSegment, error) {
+	var segments []pathSegment
+	for _, segment := range strings.Split(path, ".") {
+		s := pathSegment{name: segment}
+		if i := strings.IndexByte(segment, '['); i >= 0 {
+			s.name = segment[:i]
+			for _, index := range strings.Split(segment[i+1:], "[") {
+				if !strings.HasSuffix(index, "]") {
+					return nil, fmt.Errorf("gava: invalid path %q", path)
+				}
+				s.indexes = append(s.indexes, strings.TrimSuffix(index, "]"))
+			}
+		}
+		segments = append(segments, s)
+	}
+	return segments, nil
+}
+
+func (cd *ClassDetails) resolve(path string) (*slot, error) {
+	segments, err := parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	s := &slot{}
+	var cur interface{} = cd
+	for _, segment := range segments {
+		name, indexes := segment.name, segment.indexes
+		obj, ok := cur.(*ClassDetails)
+		if !ok || obj == nil {
+			return nil, fmt.Errorf("gava: %s: %s is not an object", path, name)
+		}
+		f := obj.Field(name)
+		if f == nil {
+			return nil, fmt.Errorf("gava: %s: %s has no field %s", path, obj.ClassName, name)
+		}
+		s.objects = append(s.objects, obj)
+		s.fields = append(s.fields, f)
+		s.array = nil
+		cur = f.Data
+		for _, index := range indexes {
+			array, ok := cur.(*JavaArray)
+			if !ok || array == nil {
+				return nil, fmt.Errorf("gava: %s: %s is not an array", path, name)
+			}
+			i, err := strconv.Atoi(index)
+			if err != nil || i < 0 || i >= len(array.Elements) {
+				return nil, fmt.Errorf("gava: %s: invalid index %s", path, index)
+			}
+			s.array, s.index = array, i
+			cur = array.Elements[i]
+		}
+	}
+	return s, nil
+}
+
+// fieldValue checks that v suits a field or array element of the given type code and
+// converts Go ints and strings.
+func fieldValue(typeCode byte, v interface{}) (interface{}, error) {
+	if n, ok := v.(int); ok {
+		return intValue(typeCode, int64(n))
+	}
+	switch typeCode {
+	case 'B':
+		if _, ok := v.(int8); ok {
+			return v, nil
+		}
+	case 'C':
+		if _, ok := v.(uint16); ok {
+			return v, nil
+		}
+	case 'S':
+		if _, ok := v.(int16); ok {
+			return v, nil
+		}
+	case 'I':
+		if _, ok := v.(int32); ok {
+			return v, nil
+		}
+	case 'J':
+		if _, ok := v.(int64); ok {
+			return v, nil
+		}
+	case 'F':
+		if _, ok := v.(float32); ok {
+			return v, nil
+		}
+	case 'D':
+		if _, ok := v.(float64); ok {
+			return v, nil
+		}
+	case 'Z':
+		if _, ok := v.(bool); ok {
+			return v, nil
+		}
+	case '[':
+		switch v := v.(type) {
+		case nil:
+			return nil, nil
+		case *JavaArray:
+			if v == nil {
+				return nil, nil
+			}
+			return v, nil
+		}
+	case 'L':
+		switch v := v.(type) {
+		case nil:
+			return nil, nil
+		case string:
+			return String(v), nil
+		case *JavaString, *ClassDetails, *JavaArray, *JavaEnum, *ClassDataDesc:
+			return v, nil
+		}
+	}
+	return nil, fmt.Errorf("cannot set %T as %c", v, typeCode)
+}
+
+// settable reports whether a slot of the type signature can hold v. Unlike in a parsed
+// stream, objects set are never class descriptors.
+func settable(signature string, v interface{}) bool {
+	if obj, ok := v.(*ClassDetails); ok && obj != nil {
+		return assignableObject(signature)
+	}
+	return assignable(signature, v)
+}
+
+func intValue(typeCode byte, n int64) (interface{}, error) {
+	switch {
+	case typeCode == 'B' && n >= math.MinInt8 && n <= math.MaxInt8:
+		return int8(n), nil
+	case typeCode == 'C' && n >= 0 && n <= math.MaxUint16:
+		return uint16(n), nil
+	case typeCode == 'S' && n >= math.MinInt16 && n <= math.MaxInt16:
+		return int16(n), nil
+	case typeCode == 'I' && n >= math.MinInt32 && n <= math.MaxInt32:
+		return int32(n), nil
+	case typeCode == 'J':
+		return n, nil
+	}
+	return nil, fmt.Errorf("cannot set %d as %c", n, typeCode)
+}
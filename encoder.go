@@ -0,0 +1,585 @@
+package gava
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"unicode/utf16"
+)
+
+// arraySUIDs are the serialVersionUIDs Java computes for common array classes, used for
+// arrays built in Go. Java doesn't check the serialVersionUID of arrays.
+var arraySUIDs = map[string]int64{
+	"[B":                  -5984413125824719648,
+	"[C":                  -5753798564021173076,
+	"[D":                  4514449696888150558,
+	"[F":                  836686056779680834,
+	"[I":                  5600894804908749477,
+	"[J":                  8655923659555304851,
+	"[S":                  -1188055269542874886,
+	"[Z":                  6309297032502205922,
+	"[Ljava.lang.Object;": -8012369246846506644,
+	"[Ljava.lang.String;": -5921575005990323385,
+}
+
+// Encoder writes objects to an output stream in the ObjectOutputStream protocol version 2.
+//
+// It writes the same values the parser returns: *ClassDetails objects, *JavaString,
+// *JavaArray and *JavaEnum values, *ClassDataDesc for TC_CLASS, BlockData, Reset and nil, as well
+// as Go strings. A value encoded a second time is written as a TC_REFERENCE to the first,
+// so shared and cyclic references are kept. Objects built in Go may leave out class
+// descriptors, one is made from the class name, serialVersionUID, flags and fields of
+// the object.
+type Encoder struct {
+	w             io.Writer
+	buf           bytes.Buffer
+	headerWritten bool
+	handleValue   int
+	handles       map[interface{}]int
+	typeStrings   map[string]int
+	descs         map[string]*ClassDetails
+}
+
+// EncodeError reports a value that can't be written.
+type EncodeError struct {
+	Err error
+}
+
+func (e *EncodeError) Error() string {
+	return "gava: " + e.Err.Error()
+}
+
+func (e *EncodeError) Unwrap() error {
+	return e.Err
+}
+
+// NewEncoder returns an Encoder writing to w, the stream header is written with the first value.
+func NewEncoder(w io.Writer) *Encoder {
+	e := &Encoder{w: w}
+	e.resetHandles()
+	return e
+}
+
+// Encode writes v as the next content element of the stream. A value that can't be
+// written leaves the stream and the handles of the Encoder as they were.
+func (e *Encoder) Encode(v interface{}) (err error) {
+	defer e.recoverError(&err, e.headerWritten, e.handleValue)
+	e.writeHeader()
+	e.writeContent(v)
+	return e.flush()
+}
+
+// Reset writes a TC_RESET, values encoded afterwards don't refer to the ones before.
+func (e *Encoder) Reset() error {
+	e.writeHeader()
+	e.writeContent(Reset{})
+	return e.flush()
+}
+
+func (e *Encoder) resetHandles() {
+	e.handleValue = 0x7e0000
+	e.handles = map[interface{}]int{}
+	e.typeStrings = map[string]int{}
+	e.descs = map[string]*ClassDetails{}
+}
+
+func (e *Encoder) writeHeader() {
+	if e.headerWritten {
+		return
+	}
+	e.buf.Write([]byte{0xac, 0xed, 0x00, 0x05})
+	e.headerWritten = true
+}
+
+func (e *Encoder) flush() error {
+	_, err := e.w.Write(e.buf.Bytes())
+	e.buf.Reset()
+	return err
+}
+
+// fail stops encoding, the error is returned by Encode.
+func (e *Encoder) fail(format string, args ...interface{}) {
+	panic(&EncodeError{Err: fmt.Errorf(format, args...)})
+}
+
+func (e *Encoder) recoverError(err *error, headerWritten bool, handleValue int) {
+	if r := recover(); r != nil {
+		ee, ok := r.(*EncodeError)
+		if !ok {
+			panic(r)
+		}
+		e.buf.Reset()
+		e.rollback(headerWritten, handleValue)
+		*err = ee
+	}
+}
+
+// rollback forgets the header and the handles from handleValue on, assigned by a failed
+// Encode that wrote nothing.
+func (e *Encoder) rollback(headerWritten bool, handleValue int) {
+	e.headerWritten = headerWritten
+	e.handleValue = handleValue
+	for v, handle := range e.handles {
+		if handle >= handleValue {
+			delete(e.handles, v)
+		}
+	}
+	for s, handle := range e.typeStrings {
+		if handle >= handleValue {
+			delete(e.typeStrings, s)
+		}
+	}
+	for name, desc := range e.descs {
+		if _, ok := e.handles[desc]; !ok {
+			delete(e.descs, name)
+		}
+	}
+}
+
+// newHandle assigns the next wire handle to v, the same way the reader numbers them.
+func (e *Encoder) newHandle(v interface{}) {
+	if v != nil {
+		e.handles[v] = e.handleValue
+	}
+	e.handleValue++
+}
+
+// writeReference writes a TC_REFERENCE when v was written before.
+func (e *Encoder) writeReference(v interface{}) bool {
+	handle, ok := e.handles[v]
+	if !ok {
+		return false
+	}
+	e.buf.WriteByte(0x71) //TC_REFERENCE
+	e.writeInt(int32(handle))
+	return true
+}
+
+func (e *Encoder) writeContent(v interface{}) {
+	switch v := v.(type) {
+	case nil:
+		e.buf.WriteByte(0x70) //TC_NULL
+	case *ClassDetails:
+		if v == nil {
+			e.buf.WriteByte(0x70)
+			return
+		}
+		e.writeObject(v)
+	case *JavaString:
+		if v == nil {
+			e.buf.WriteByte(0x70)
+			return
+		}
+		if !e.writeReference(v) {
+			e.writeString(v.utf(), v.long, v)
+		}
+	case string:
+		e.writeString(encodeModifiedUTF8(v), false, nil)
+	case *JavaArray:
+		if v == nil {
+			e.buf.WriteByte(0x70)
+			return
+		}
+		e.writeArray(v)
+	case *JavaEnum:
+		if v == nil {
+			e.buf.WriteByte(0x70)
+			return
+		}
+		e.writeEnum(v)
+	case *ClassDataDesc:
+		if v == nil {
+			e.buf.WriteByte(0x70)
+			return
+		}
+		if v.descriptor {
+			e.writeClassDesc(v.ClassDetail)
+			return
+		}
+		e.writeClass(v)
+	case BlockData:
+		e.writeBlockData(v)
+	case Reset:
+		e.buf.WriteByte(0x79) //TC_RESET
+		e.resetHandles()
+	default:
+		e.fail("cannot encode %T", v)
+	}
+}
+
+func (e *Encoder) writeBlockData(b BlockData) {
+	if len(b) <= 0xff {
+		e.buf.WriteByte(0x77) //TC_BLOCKDATA
+		e.buf.WriteByte(byte(len(b)))
+	} else {
+		e.buf.WriteByte(0x7a) //TC_BLOCKDATALONG
+		e.writeInt(int32(len(b)))
+	}
+	e.buf.Write(b)
+}
+
+// writeString writes the modified UTF-8 bytes of a string as TC_STRING, or TC_LONGSTRING
+// when it is longer than 65535 bytes or long is set.
+func (e *Encoder) writeString(b []byte, long bool, handle interface{}) {
+	if len(b) <= 0xffff && !long {
+		e.buf.WriteByte(0x74) //TC_STRING
+		e.newHandle(handle)
+		e.writeShort(uint16(len(b)))
+	} else {
+		e.buf.WriteByte(0x7c) //TC_LONGSTRING
+		e.newHandle(handle)
+		e.writeLong(int64(len(b)))
+	}
+	e.buf.Write(b)
+}
+
+// writeUtf writes s with a 2 byte length, as the bytes of raw while s is still the string raw holds.
+func (e *Encoder) writeUtf(s string, raw []byte) {
+	b := utfBytes(s, raw)
+	if len(b) > 0xffff {
+		e.fail("%q is too long", s)
+	}
+	e.writeShort(uint16(len(b)))
+	e.buf.Write(b)
+}
+
+// writeTypeString writes the type of an object field, Java shares these strings between descriptors.
+func (e *Encoder) writeTypeString(s string) {
+	if handle, ok := e.typeStrings[s]; ok {
+		e.buf.WriteByte(0x71) //TC_REFERENCE
+		e.writeInt(int32(handle))
+		return
+	}
+	e.typeStrings[s] = e.handleValue
+	e.writeString(encodeModifiedUTF8(s), false, nil)
+}
+
+func (e *Encoder) writeObject(obj *ClassDetails) {
+	if e.writeReference(obj) {
+		return
+	}
+	e.buf.WriteByte(0x73) //TC_OBJECT
+	e.writeClassDesc(e.objectDescs(obj))
+	e.newHandle(obj)
+
+	var parts []*ClassDetails
+	for part := obj; part != nil; part = part.SuperClass {
+		parts = append(parts, part)
+	}
+	for i := len(parts) - 1; i >= 0; i-- {
+		part := parts[i]
+		if part.ClassDescFlags&0x02 == 0x02 {
+			for _, f := range part.FieldDescription {
+				e.writeFieldValue(f.TypeCode, f.Data)
+			}
+		}
+		if part.ClassDescFlags&0x04 == 0x04 && part.ClassDescFlags&0x08 == 0 {
+			e.fail("externalizable class %s without SC_BLOCKDATA is not supported", part.ClassName)
+		}
+		if (part.ClassDescFlags&0x02 == 0x02 && part.ClassDescFlags&0x01 == 0x01) || part.ClassDescFlags&0x04 == 0x04 {
+			for _, v := range part.Annotation {
+				e.writeContent(v)
+			}
+			e.buf.WriteByte(0x78) //TC_ENDBLOCKDATA
+		}
+	}
+}
+
+// objectDescs returns the class descriptors of the parts of obj, making descriptors for
+// objects built in Go.
+func (e *Encoder) objectDescs(obj *ClassDetails) []*ClassDetails {
+	var descs []*ClassDetails
+	for part := obj; part != nil; part = part.SuperClass {
+		desc := part.desc
+		if desc == nil && part.ProxyInterfaces == nil && describes(e.descs[part.ClassName], part) {
+			desc = e.descs[part.ClassName]
+		}
+		if desc == nil {
+			desc = &ClassDetails{
+				ClassName:        part.ClassName,
+				SerialVersionUID: part.SerialVersionUID,
+				ClassDescFlags:   part.ClassDescFlags,
+				ProxyInterfaces:  part.ProxyInterfaces,
+			}
+			for _, f := range part.FieldDescription {
+				desc.FieldDescription = append(desc.FieldDescription, &ClassField{
+					TypeCode:  f.TypeCode,
+					Name:      f.Name,
+					className: fieldClassName(f),
+				})
+			}
+			if part.ProxyInterfaces == nil {
+				e.descs[part.ClassName] = desc
+			}
+		}
+		descs = append(descs, desc)
+	}
+	return descs
+}
+
+// describes reports whether desc has the serialVersionUID, flags and fields of the object part.
+func describes(desc, part *ClassDetails) bool {
+	if desc == nil || desc.SerialVersionUID != part.SerialVersionUID || desc.ClassDescFlags != part.ClassDescFlags ||
+		len(desc.FieldDescription) != len(part.FieldDescription) {
+		return false
+	}
+	for i, f := range desc.FieldDescription {
+		if f.Name != part.FieldDescription[i].Name || f.TypeCode != part.FieldDescription[i].TypeCode {
+			return false
+		}
+	}
+	return true
+}
+
+// fieldClassName returns the type string of an object field, taken from its value when
+// the field was built in Go.
+func fieldClassName(f *ClassField) string {
+	if f.className != "" || (f.TypeCode != 'L' && f.TypeCode != '[') {
+		return f.className
+	}
+	switch v := f.Data.(type) {
+	case *JavaString, string:
+		return "Ljava/lang/String;"
+	case *JavaArray:
+		return strings.Replace(v.ClassName, ".", "/", -1)
+	case *JavaEnum:
+		return "L" + strings.Replace(v.ClassName, ".", "/", -1) + ";"
+	case *ClassDetails:
+		if len(v.ProxyInterfaces) > 0 {
+			return "L" + strings.Replace(v.ProxyInterfaces[0], ".", "/", -1) + ";"
+		}
+		return "L" + strings.Replace(v.ClassName, ".", "/", -1) + ";"
+	case *ClassDataDesc:
+		return "Ljava/lang/Class;"
+	}
+	return "Ljava/lang/Object;"
+}
+
+// writeClassDesc writes the descriptor of descs[0] with descs[1:] as its super classes.
+func (e *Encoder) writeClassDesc(descs []*ClassDetails) {
+	if len(descs) == 0 {
+		e.buf.WriteByte(0x70) //TC_NULL
+		return
+	}
+	desc := descs[0]
+	if e.writeReference(desc) {
+		return
+	}
+	if desc.ProxyInterfaces != nil {
+		e.writeProxyClassDesc(descs)
+		return
+	}
+	e.buf.WriteByte(0x72) //TC_CLASSDESC
+	e.writeUtf(desc.ClassName, desc.rawName)
+	e.writeLong(desc.SerialVersionUID)
+	e.newHandle(desc)
+	if _, ok := e.descs[desc.ClassName]; !ok {
+		//Objects built in Go refer to the descriptors already written for their class
+		e.descs[desc.ClassName] = desc
+	}
+	e.buf.WriteByte(desc.ClassDescFlags)
+	e.writeShort(uint16(len(desc.FieldDescription)))
+	for _, f := range desc.FieldDescription {
+		e.buf.WriteByte(f.TypeCode)
+		e.writeUtf(f.Name, f.rawName)
+		if f.TypeCode == 'L' || f.TypeCode == '[' {
+			if f.typeString != nil && f.typeString.Value == f.className {
+				e.writeContent(f.typeString)
+			} else {
+				e.writeTypeString(f.className)
+			}
+		}
+	}
+	for _, v := range desc.Annotation {
+		e.writeContent(v)
+	}
+	e.buf.WriteByte(0x78) //TC_ENDBLOCKDATA
+	e.writeClassDesc(descs[1:])
+}
+
+func (e *Encoder) writeProxyClassDesc(descs []*ClassDetails) {
+	desc := descs[0]
+	e.buf.WriteByte(0x7d) //TC_PROXYCLASSDESC
+	e.newHandle(desc)
+	e.writeInt(int32(len(desc.ProxyInterfaces)))
+	for i, name := range desc.ProxyInterfaces {
+		var raw []byte
+		if i < len(desc.rawInterfaces) {
+			raw = desc.rawInterfaces[i]
+		}
+		e.writeUtf(name, raw)
+	}
+	for _, v := range desc.Annotation {
+		e.writeContent(v)
+	}
+	e.buf.WriteByte(0x78) //TC_ENDBLOCKDATA
+	e.writeClassDesc(descs[1:])
+}
+
+func (e *Encoder) writeClass(cdd *ClassDataDesc) {
+	if e.writeReference(cdd) {
+		return
+	}
+	e.buf.WriteByte(0x76) //TC_CLASS
+	e.writeClassDesc(cdd.ClassDetail)
+	e.newHandle(cdd)
+}
+
+func (e *Encoder) writeArray(array *JavaArray) {
+	if e.writeReference(array) {
+		return
+	}
+	if len(array.ClassName) < 2 || array.ClassName[0] != '[' {
+		e.fail("invalid array class %q", array.ClassName)
+	}
+	e.buf.WriteByte(0x75) //TC_ARRAY
+	if array.desc != nil {
+		e.writeClassDesc(array.desc.ClassDetail)
+	} else {
+		desc := e.descs[array.ClassName]
+		if desc == nil {
+			desc = &ClassDetails{ClassName: array.ClassName, SerialVersionUID: arraySUIDs[array.ClassName], ClassDescFlags: 0x02}
+			e.descs[array.ClassName] = desc
+		}
+		e.writeClassDesc([]*ClassDetails{desc})
+	}
+	e.newHandle(array)
+	e.writeInt(int32(len(array.Elements)))
+	for _, v := range array.Elements {
+		e.writeFieldValue(array.ClassName[1], v)
+	}
+}
+
+func (e *Encoder) writeEnum(enum *JavaEnum) {
+	if e.writeReference(enum) {
+		return
+	}
+	e.buf.WriteByte(0x7e) //TC_ENUM
+	if enum.desc != nil {
+		e.writeClassDesc(enum.desc.ClassDetail)
+	} else {
+		desc := e.descs[enum.ClassName]
+		if desc == nil {
+			desc = &ClassDetails{ClassName: enum.ClassName, ClassDescFlags: 0x12}
+			e.descs[enum.ClassName] = desc
+		}
+		base := e.descs["java.lang.Enum"]
+		if base == nil {
+			base = &ClassDetails{ClassName: "java.lang.Enum", ClassDescFlags: 0x12}
+			e.descs["java.lang.Enum"] = base
+		}
+		e.writeClassDesc([]*ClassDetails{desc, base})
+	}
+	e.newHandle(enum)
+	if enum.constant != nil && enum.constant.Value == enum.Constant {
+		e.writeContent(enum.constant)
+	} else {
+		e.writeString(encodeModifiedUTF8(enum.Constant), false, nil)
+	}
+}
+
+// writeFieldValue writes a field or array element value of the given type code.
+func (e *Encoder) writeFieldValue(typeCode byte, v interface{}) {
+	switch typeCode {
+	case 'B':
+		e.buf.WriteByte(byte(e.integer(typeCode, v)))
+	case 'C':
+		e.writeShort(uint16(e.integer(typeCode, v)))
+	case 'D':
+		e.writeLong(int64(math.Float64bits(e.float(typeCode, v))))
+	case 'F':
+		f, ok := v.(float32)
+		if !ok {
+			f = float32(e.float(typeCode, v))
+		}
+		e.writeInt(int32(math.Float32bits(f)))
+	case 'I':
+		e.writeInt(int32(e.integer(typeCode, v)))
+	case 'J':
+		e.writeLong(e.integer(typeCode, v))
+	case 'S':
+		e.writeShort(uint16(e.integer(typeCode, v)))
+	case 'Z':
+		b, ok := v.(bool)
+		if !ok {
+			e.fail("cannot encode %T as Z", v)
+		}
+		if b {
+			e.buf.WriteByte(1)
+		} else {
+			e.buf.WriteByte(0)
+		}
+	case '[', 'L':
+		e.writeContent(v)
+	default:
+		e.fail("invalid field type code %q", typeCode)
+	}
+}
+
+func (e *Encoder) integer(typeCode byte, v interface{}) int64 {
+	switch i := v.(type) {
+	case int:
+		return int64(i)
+	case uint8:
+		return int64(i)
+	case uint32:
+		return int64(i)
+	}
+	i, ok := integer(v)
+	if !ok {
+		e.fail("cannot encode %T as %c", v, typeCode)
+	}
+	return i
+}
+
+func (e *Encoder) float(typeCode byte, v interface{}) float64 {
+	switch f := v.(type) {
+	case float32:
+		return float64(f)
+	case float64:
+		return f
+	}
+	e.fail("cannot encode %T as %c", v, typeCode)
+	return 0
+}
+
+func (e *Encoder) writeShort(v uint16) {
+	var b [2]byte
+	binary.BigEndian.PutUint16(b[:], v)
+	e.buf.Write(b[:])
+}
+
+func (e *Encoder) writeInt(v int32) {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], uint32(v))
+	e.buf.Write(b[:])
+}
+
+func (e *Encoder) writeLong(v int64) {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(v))
+	e.buf.Write(b[:])
+}
+
+// encodeModifiedUTF8 encodes s the way DataOutput.writeUTF does: NUL takes two bytes and
+// characters outside the BMP are written as surrogate pairs.
+func encodeModifiedUTF8(s string) []byte {
+	if isASCII([]byte(s)) {
+		return []byte(s)
+	}
+	b := make([]byte, 0, len(s))
+	for _, c := range utf16.Encode([]rune(s)) {
+		switch {
+		case c != 0 && c < 0x80:
+			b = append(b, byte(c))
+		case c < 0x800:
+			b = append(b, byte(0xc0|c>>6), byte(0x80|c&0x3f))
+		default:
+			b = append(b, byte(0xe0|c>>12), byte(0x80|(c>>6)&0x3f), byte(0x80|c&0x3f))
+		}
+	}
+	return b
+}
@@ -0,0 +1,59 @@
+package gava
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrStreamMagic is returned when the data doesn't start with the 0xaced stream magic.
+var ErrStreamMagic = errors.New("gava: invalid STREAM_MAGIC, should be 0xac ed")
+
+// ParseError reports an invalid stream and the offset where the problem was found.
+type ParseError struct {
+	Offset int
+	Err    error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%v (offset %d)", e.Err, e.Offset)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+func (g *GavaDeserilizer) offset() int {
+	return g.size - len(g.data)
+}
+
+// fail stops decoding, the error is returned by the exported entry points.
+func (g *GavaDeserilizer) fail(msg string) {
+	g.failWith(errors.New(msg))
+}
+
+func (g *GavaDeserilizer) failWith(err error) {
+	panic(&ParseError{Offset: g.offset(), Err: err})
+}
+
+// recoverError turns a failure, or reading past the end of a truncated stream, into the error of the caller.
+func (g *GavaDeserilizer) recoverError(err *error) {
+	if r := recover(); r != nil {
+		*err = g.parseError(r)
+	}
+}
+
+// parseError returns the error of a recovered failure, and panics again with anything else.
+func (g *GavaDeserilizer) parseError(r interface{}) *ParseError {
+	if e, ok := r.(*ParseError); ok {
+		return e
+	}
+	panic(r)
+}
+
+// need fails with io.ErrUnexpectedEOF when fewer than n bytes of the stream are left.
+func (g *GavaDeserilizer) need(n int) {
+	if len(g.data) < n {
+		g.failWith(io.ErrUnexpectedEOF)
+	}
+}
@@ -0,0 +1,238 @@
+package gava
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// JavaException is a java.lang.Throwable read from the stream.
+type JavaException struct {
+	ClassName  string
+	Message    string
+	HasMessage bool
+	Cause      *JavaException
+	Suppressed []*JavaException
+	StackTrace []StackTraceElement
+}
+
+// StackTraceElement is a java.lang.StackTraceElement, the module and class loader
+// names are only written by Java 9 and later.
+type StackTraceElement struct {
+	ClassLoaderName string
+	ModuleName      string
+	ModuleVersion   string
+	DeclaringClass  string
+	MethodName      string
+	FileName        string
+	LineNumber      int32
+}
+
+func init() {
+	RegisterClassReader("java.lang.Throwable", readThrowable)
+	RegisterClassReader("java.lang.StackTraceElement", readStackTraceElement)
+}
+
+// Error formats the exception the same way Throwable.toString does.
+func (e *JavaException) Error() string {
+	if !e.HasMessage {
+		return e.ClassName
+	}
+	return e.ClassName + ": " + e.Message
+}
+
+func (e *JavaException) Unwrap() error {
+	if e.Cause == nil {
+		return nil
+	}
+	return e.Cause
+}
+
+// PrintStackTrace writes the exception with its stack trace, suppressed exceptions and
+// causes in the format of Throwable.printStackTrace.
+func (e *JavaException) PrintStackTrace(w io.Writer) {
+	seen := map[*JavaException]bool{e: true}
+	fmt.Fprintln(w, e.Error())
+	for _, ste := range e.StackTrace {
+		fmt.Fprintln(w, "\tat "+ste.String())
+	}
+	for _, se := range e.Suppressed {
+		se.printEnclosedStackTrace(w, e.StackTrace, "Suppressed: ", "\t", seen)
+	}
+	if e.Cause != nil {
+		e.Cause.printEnclosedStackTrace(w, e.StackTrace, "Caused by: ", "", seen)
+	}
+}
+
+// StackTraceString returns the output of PrintStackTrace.
+func (e *JavaException) StackTraceString() string {
+	var b strings.Builder
+	e.PrintStackTrace(&b)
+	return b.String()
+}
+
+func (e *JavaException) printEnclosedStackTrace(w io.Writer, enclosingTrace []StackTraceElement, caption, prefix string, seen map[*JavaException]bool) {
+	if seen[e] {
+		fmt.Fprintln(w, prefix+caption+"[CIRCULAR REFERENCE: "+e.Error()+"]")
+		return
+	}
+	seen[e] = true
+
+	//Compute number of frames in common between this and enclosing trace
+	m := len(e.StackTrace) - 1
+	n := len(enclosingTrace) - 1
+	for m >= 0 && n >= 0 && e.StackTrace[m] == enclosingTrace[n] {
+		m--
+		n--
+	}
+	framesInCommon := len(e.StackTrace) - 1 - m
+
+	fmt.Fprintln(w, prefix+caption+e.Error())
+	for i := 0; i <= m; i++ {
+		fmt.Fprintln(w, prefix+"\tat "+e.StackTrace[i].String())
+	}
+	if framesInCommon != 0 {
+		fmt.Fprintf(w, "%s\t... %d more\n", prefix, framesInCommon)
+	}
+	for _, se := range e.Suppressed {
+		se.printEnclosedStackTrace(w, e.StackTrace, "Suppressed: ", prefix+"\t", seen)
+	}
+	if e.Cause != nil {
+		e.Cause.printEnclosedStackTrace(w, e.StackTrace, "Caused by: ", prefix, seen)
+	}
+}
+
+// String formats the element the same way StackTraceElement.toString does.
+func (ste StackTraceElement) String() string {
+	s := ""
+	if ste.ClassLoaderName != "" {
+		s += ste.ClassLoaderName + "/"
+	}
+	if ste.ModuleName != "" {
+		s += ste.ModuleName
+		if ste.ModuleVersion != "" {
+			s += "@" + ste.ModuleVersion
+		}
+	}
+	if s != "" && !strings.HasSuffix(s, "/") {
+		s += "/"
+	}
+	s += ste.DeclaringClass + "." + ste.MethodName + "("
+	switch {
+	case ste.LineNumber == -2:
+		s += "Native Method"
+	case ste.FileName == "":
+		s += "Unknown Source"
+	case ste.LineNumber >= 0:
+		s += fmt.Sprintf("%s:%d", ste.FileName, ste.LineNumber)
+	default:
+		s += ste.FileName
+	}
+	return s + ")"
+}
+
+func readStackTraceElement(obj *ClassDetails) (interface{}, error) {
+	var ste StackTraceElement
+	for name, s := range map[string]*string{
+		"classLoaderName": &ste.ClassLoaderName,
+		"moduleName":      &ste.ModuleName,
+		"moduleVersion":   &ste.ModuleVersion,
+		"declaringClass":  &ste.DeclaringClass,
+		"methodName":      &ste.MethodName,
+		"fileName":        &ste.FileName,
+	} {
+		if obj.Field(name) == nil {
+			continue
+		}
+		v, err := stringField(obj, name)
+		if err != nil {
+			return nil, err
+		}
+		*s = v
+	}
+	line, err := intField(obj, "lineNumber")
+	if err != nil {
+		return nil, err
+	}
+	ste.LineNumber = line
+	return ste, nil
+}
+
+// readThrowable reads the Throwable fields, a cause that refers to the exception
+// itself means the cause was never set.
+func readThrowable(obj *ClassDetails) (interface{}, error) {
+	e := throwable(obj)
+
+	message, err := fieldData(obj, "detailMessage")
+	if err != nil {
+		return nil, err
+	}
+	if message != nil {
+		e.Message, err = stringField(obj, "detailMessage")
+		if err != nil {
+			return nil, err
+		}
+		e.HasMessage = true
+	}
+
+	if f := obj.Field("cause"); f != nil && f.Data != obj {
+		e.Cause, err = exceptionValue(f.Data)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if f := obj.Field("stackTrace"); f != nil && f.Data != nil {
+		trace, ok := decodedValue(f.Data).([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("field stackTrace is %T, not an array", f.Data)
+		}
+		for _, v := range trace {
+			ste, ok := v.(StackTraceElement)
+			if !ok {
+				return nil, fmt.Errorf("stack trace element is %T", v)
+			}
+			e.StackTrace = append(e.StackTrace, ste)
+		}
+	}
+
+	if f := obj.Field("suppressedExceptions"); f != nil && f.Data != nil {
+		suppressed, ok := decodedValue(f.Data).([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("field suppressedExceptions is %T, not a list", f.Data)
+		}
+		for _, v := range suppressed {
+			se, err := exceptionValue(v)
+			if err != nil {
+				return nil, err
+			}
+			e.Suppressed = append(e.Suppressed, se)
+		}
+	}
+	return e, nil
+}
+
+// throwable returns the JavaException of obj, creating it ahead of the reader when an
+// exception refers to one that is still being read, such as a cause cycle.
+func throwable(obj *ClassDetails) *JavaException {
+	e, ok := obj.Decoded.(*JavaException)
+	if !ok {
+		e = &JavaException{ClassName: obj.ClassName}
+		obj.Decoded = e
+	}
+	return e
+}
+
+func exceptionValue(v interface{}) (*JavaException, error) {
+	if obj, ok := v.(*ClassDetails); ok && obj.Decoded == nil && classPart(obj, "java.lang.Throwable") != nil {
+		return throwable(obj), nil
+	}
+	switch v := decodedValue(v).(type) {
+	case nil:
+		return nil, nil
+	case *JavaException:
+		return v, nil
+	default:
+		return nil, fmt.Errorf("%T is not a Throwable", v)
+	}
+}
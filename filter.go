@@ -0,0 +1,143 @@
+package gava
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// FilterStatus is the decision of an InputFilter.
+type FilterStatus int
+
+const (
+	// FilterUndecided leaves the decision to the rest of the filter, the value is accepted
+	// when nothing rejects it.
+	FilterUndecided FilterStatus = iota
+	FilterAllowed
+	FilterRejected
+)
+
+// FilterInfo describes a checkpoint of an InputFilter, like ObjectInputFilter.FilterInfo.
+type FilterInfo struct {
+	// Class is the class of a new class descriptor or array, and empty for a reference.
+	Class string
+	// ArrayLength is the length of a new array, or -1.
+	ArrayLength int
+	// Depth is the nesting of the objects and arrays being read, 1 for top-level ones.
+	Depth int
+	// References is the number of values and references read so far.
+	References int
+	// StreamBytes is the number of bytes read so far.
+	StreamBytes int
+}
+
+// InputFilter is called for every new class descriptor, array and reference of a stream, at
+// the checkpoints of Java's ObjectInputFilter. Decoding stops with a *FilterError when it
+// returns FilterRejected.
+type InputFilter func(info FilterInfo) FilterStatus
+
+// FilterError is returned, wrapped in a *ParseError, when an InputFilter rejects the stream.
+type FilterError struct {
+	Info FilterInfo
+}
+
+func (e *FilterError) Error() string {
+	if e.Info.Class == "" {
+		return fmt.Sprintf("gava: filter rejected reference at depth %d after %d references", e.Info.Depth, e.Info.References)
+	}
+	if e.Info.ArrayLength >= 0 {
+		return fmt.Sprintf("gava: filter rejected %s of length %d", e.Info.Class, e.Info.ArrayLength)
+	}
+	return "gava: filter rejected class " + e.Info.Class
+}
+
+// filter runs the filter of the options, if any, at a checkpoint.
+func (g *GavaDeserilizer) filter(class string, arrayLength int) {
+	if g.opts.Filter == nil {
+		return
+	}
+	info := FilterInfo{
+		Class:       class,
+		ArrayLength: arrayLength,
+		Depth:       g.nesting,
+		References:  g.objects + g.references,
+		StreamBytes: g.offset(),
+	}
+	if g.opts.Filter(info) == FilterRejected {
+		g.failWith(&FilterError{Info: info})
+	}
+}
+
+// ParseFilter returns the filter of a pattern in the syntax of the jdk.serialFilter
+// property, such as "maxdepth=10;com.acme.*;!*". Patterns are separated by semicolons:
+//
+//	maxdepth=n, maxrefs=n, maxbytes=n, maxarray=n   reject streams beyond the limit
+//	com.acme.Order    allow the class
+//	com.acme.*        allow the classes of the package
+//	com.acme.**       allow the classes of the package and its sub-packages
+//	com.acme.Ord*     allow the classes whose name starts with com.acme.Ord
+//	*                 allow every class
+//	!pattern          reject the classes matching pattern
+//
+// Arrays are checked by their element class. The first pattern matching a class decides,
+// classes no pattern matches are left undecided. Module names before a slash are ignored,
+// the stream doesn't tell the module of a class.
+func ParseFilter(pattern string) (InputFilter, error) {
+	limits := map[string]int{}
+	type rule struct {
+		pattern string
+		status  FilterStatus
+	}
+	var rules []rule
+	for _, p := range strings.Split(pattern, ";") {
+		if p == "" {
+			continue
+		}
+		if i := strings.IndexByte(p, '='); i >= 0 {
+			name := p[:i]
+			switch name {
+			case "maxdepth", "maxrefs", "maxbytes", "maxarray":
+			default:
+				return nil, fmt.Errorf("gava: unknown filter limit %q", p)
+			}
+			n, err := strconv.ParseInt(p[i+1:], 10, 64)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("gava: invalid filter limit %q", p)
+			}
+			limits[name] = int(n)
+			continue
+		}
+		r := rule{pattern: p, status: FilterAllowed}
+		if strings.HasPrefix(r.pattern, "!") {
+			r.pattern, r.status = r.pattern[1:], FilterRejected
+		}
+		if i := strings.IndexByte(r.pattern, '/'); i >= 0 {
+			r.pattern = r.pattern[i+1:]
+		}
+		if r.pattern == "" || strings.Contains(strings.TrimRight(r.pattern, "*"), "*") {
+			return nil, fmt.Errorf("gava: invalid filter pattern %q", p)
+		}
+		rules = append(rules, r)
+	}
+	exceeds := func(name string, v int) bool {
+		max, ok := limits[name]
+		return ok && v > max
+	}
+
+	return func(info FilterInfo) FilterStatus {
+		if exceeds("maxdepth", info.Depth) || exceeds("maxrefs", info.References) || exceeds("maxbytes", info.StreamBytes) ||
+			(info.ArrayLength >= 0 && exceeds("maxarray", info.ArrayLength)) {
+			return FilterRejected
+		}
+		class, ok := elementClass(info.Class)
+		if info.Class == "" || !ok {
+			return FilterUndecided
+		}
+		for _, r := range rules {
+			if matchClass(r.pattern, class) {
+				return r.status
+			}
+		}
+		return FilterUndecided
+	}, nil
+}
@@ -0,0 +1,189 @@
+package gava
+
+import "fmt"
+
+const guavaCollect = "com.google.common.collect."
+
+func init() {
+	RegisterClassReader(guavaCollect+"ImmutableList$SerializedForm", readGuavaElements)
+	RegisterClassReader(guavaCollect+"ImmutableSet$SerializedForm", readGuavaElements)
+	RegisterClassReader(guavaCollect+"ImmutableSortedSet$SerializedForm", readGuavaElements)
+	RegisterClassReader(guavaCollect+"ImmutableAsList$SerializedForm", readGuavaAsList)
+	RegisterClassReader(guavaCollect+"ImmutableMap$SerializedForm", readGuavaMap)
+	RegisterClassReader(guavaCollect+"ImmutableMultiset$SerializedForm", readGuavaMultisetForm)
+	RegisterClassReader(guavaCollect+"ImmutableEnumSet$EnumSerializedForm", readGuavaEnumSetForm)
+	RegisterClassReader(guavaCollect+"ImmutableEnumMap$EnumSerializedForm", readGuavaEnumMapForm)
+	RegisterClassReader(guavaCollect+"ImmutableListMultimap", multimapReader(guavaCollect+"ImmutableListMultimap", false))
+	RegisterClassReader(guavaCollect+"ImmutableSetMultimap", multimapReader(guavaCollect+"ImmutableSetMultimap", true))
+	RegisterClassReader(guavaCollect+"ArrayListMultimap", multimapReader(guavaCollect+"ArrayListMultimap", false))
+	RegisterClassReader(guavaCollect+"HashMultimap", multimapReader(guavaCollect+"HashMultimap", false))
+	RegisterClassReader(guavaCollect+"LinkedListMultimap", readGuavaLinkedListMultimap)
+	RegisterClassReader(guavaCollect+"HashMultiset", multisetReader(guavaCollect+"HashMultiset"))
+	RegisterClassReader(guavaCollect+"LinkedHashMultiset", multisetReader(guavaCollect+"LinkedHashMultiset"))
+}
+
+// readGuavaElements reads the elements array the immutable lists and sets are replaced with.
+func readGuavaElements(obj *ClassDetails) (interface{}, error) {
+	return sliceField(obj, "elements")
+}
+
+func readGuavaAsList(obj *ClassDetails) (interface{}, error) {
+	return sliceField(obj, "collection")
+}
+
+// readGuavaMap reads the keys and values of ImmutableMap$SerializedForm, which are arrays
+// or, since Guava 31, immutable collections. The sorted map and bimap forms extend it.
+func readGuavaMap(obj *ClassDetails) (interface{}, error) {
+	keys, err := sliceField(obj, "keys")
+	if err != nil {
+		return nil, err
+	}
+	values, err := sliceField(obj, "values")
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) != len(values) {
+		return nil, fmt.Errorf("%d keys for %d values", len(keys), len(values))
+	}
+	return newMap(keys, values), nil
+}
+
+// readGuavaMultisetForm returns the multiset as a map from each element to its count.
+func readGuavaMultisetForm(obj *ClassDetails) (interface{}, error) {
+	elements, err := sliceField(obj, "elements")
+	if err != nil {
+		return nil, err
+	}
+	counts, err := sliceField(obj, "counts")
+	if err != nil {
+		return nil, err
+	}
+	if len(elements) != len(counts) {
+		return nil, fmt.Errorf("%d elements for %d counts", len(elements), len(counts))
+	}
+	return newMap(elements, counts), nil
+}
+
+// readGuavaEnumSetForm reads the EnumSet ImmutableEnumSet is replaced with. The sets built
+// by Sets.immutableEnumSet and the toImmutableEnumSet collector of CollectCollectors are
+// ImmutableEnumSets.
+func readGuavaEnumSetForm(obj *ClassDetails) (interface{}, error) {
+	return sliceField(obj, "delegate")
+}
+
+// readGuavaEnumMapForm reads the EnumMap ImmutableEnumMap is replaced with. The maps built
+// by Maps.immutableEnumMap and the toImmutableEnumMap collectors of CollectCollectors are
+// ImmutableEnumMaps.
+func readGuavaEnumMapForm(obj *ClassDetails) (interface{}, error) {
+	v, err := fieldData(obj, "delegate")
+	if err != nil {
+		return nil, err
+	}
+	switch m := decodedValue(v).(type) {
+	case map[interface{}]interface{}:
+		return m, nil
+	case nil:
+		return map[interface{}]interface{}{}, nil
+	}
+	return nil, fmt.Errorf("field delegate is %T, not a map", v)
+}
+
+// multimapReader reads the layout of Serialization.writeMultimap: the number of keys and
+// for each key the key, the number of values and the values. The multimap is returned
+// as a map from each key to a slice of its values.
+func multimapReader(className string, valueComparator bool) ClassReader {
+	return func(obj *ClassDetails) (interface{}, error) {
+		in := NewObjectInput(classPart(obj, className))
+		if valueComparator {
+			if _, err := in.ReadObject(); err != nil {
+				return nil, err
+			}
+		}
+		size, err := in.ReadInt()
+		if err != nil {
+			return nil, err
+		}
+		var keys, values []interface{}
+		for i := 0; i < int(size); i++ {
+			key, err := in.ReadObject()
+			if err != nil {
+				return nil, err
+			}
+			count, err := in.ReadInt()
+			if err != nil {
+				return nil, err
+			}
+			v, err := readElements(in, int(count))
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, decodedValue(key))
+			values = append(values, v)
+		}
+		return newMap(keys, values), nil
+	}
+}
+
+// readGuavaLinkedListMultimap reads the number of entries followed by each key and value.
+func readGuavaLinkedListMultimap(obj *ClassDetails) (interface{}, error) {
+	in := NewObjectInput(classPart(obj, guavaCollect+"LinkedListMultimap"))
+	size, err := in.ReadInt()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := readElements(in, 2*int(size))
+	if err != nil {
+		return nil, err
+	}
+	multimap := map[interface{}]interface{}{}
+	for i := 0; i < len(entries); i += 2 {
+		//Keys that can't be Go map keys leave the multimap undecoded, as newMap does
+		if !hashable(entries[i]) {
+			return nil, nil
+		}
+		values, _ := multimap[entries[i]].([]interface{})
+		multimap[entries[i]] = append(values, entries[i+1])
+	}
+	return multimap, nil
+}
+
+// multisetReader reads the layout of Serialization.writeMultiset: the number of distinct
+// elements followed by each element and its count.
+func multisetReader(className string) ClassReader {
+	return func(obj *ClassDetails) (interface{}, error) {
+		in := NewObjectInput(classPart(obj, className))
+		size, err := in.ReadInt()
+		if err != nil {
+			return nil, err
+		}
+		var elements, counts []interface{}
+		for i := 0; i < int(size); i++ {
+			e, err := in.ReadObject()
+			if err != nil {
+				return nil, err
+			}
+			count, err := in.ReadInt()
+			if err != nil {
+				return nil, err
+			}
+			elements = append(elements, decodedValue(e))
+			counts = append(counts, count)
+		}
+		return newMap(elements, counts), nil
+	}
+}
+
+// sliceField returns the elements of an array or decoded collection field.
+func sliceField(obj *ClassDetails, name string) ([]interface{}, error) {
+	v, err := fieldData(obj, name)
+	if err != nil {
+		return nil, err
+	}
+	switch s := decodedValue(v).(type) {
+	case []interface{}:
+		return s, nil
+	case nil:
+		return []interface{}{}, nil
+	}
+	return nil, fmt.Errorf("field %s is %T, not an array or collection", name, v)
+}
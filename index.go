@@ -0,0 +1,297 @@
+package gava
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// IndexEntry is an object, array, enum or string of an indexed stream.
+type IndexEntry struct {
+	// Class is the class of the object or enum, the class of the array, or
+	// java.lang.String for strings.
+	Class  string
+	Handle int
+	// Reset is the number of TC_RESETs and TC_EXCEPTIONs before the value, handles are
+	// assigned again after each.
+	Reset int
+	Span  Span
+	// TopLevel is set for values that aren't inside another one.
+	TopLevel bool
+	tc       byte
+	// first and next are the handles assigned at the start of the value, to its class
+	// descriptor when it is new, and after the value and the values inside it.
+	first, next int
+}
+
+// Index locates the values of a stream, parsed once, and decodes them only when they are
+// accessed. Only the class descriptors and the entries are kept, along with the values
+// decoded so far, so a large stream can be searched for a few values without building all
+// of them. An Index isn't safe for concurrent use.
+type Index struct {
+	// Entries are the values of the stream in stream order.
+	Entries     []IndexEntry
+	data        []byte
+	opts        DecoderOptions
+	generations []*generation
+}
+
+// generation is the handle table of the part of a stream between resets. Values not yet
+// decoded are lazyHandles.
+type generation struct {
+	handles []interface{}
+	descs   []*ClassDataDesc
+}
+
+// lazyHandle stands for the value of an index entry not yet decoded.
+type lazyHandle int
+
+// indexer collects the entries and handle tables while a stream is indexed.
+type indexer struct {
+	entries     []IndexEntry
+	generations []*generation
+}
+
+// NewIndex parses the stream in data into an Index.
+func NewIndex(data []byte) (*Index, error) {
+	return DecoderOptions{}.NewIndex(data)
+}
+
+// NewIndex parses the stream in data into an Index, within the limits of the options. The
+// limits apply to the whole stream while it is indexed and to every value decoded later.
+// Lenient and NestedDepth are ignored.
+func (o DecoderOptions) NewIndex(data []byte) (x *Index, err error) {
+	g := o.NewGavaDeserilizer(data)
+	g.visitor = BaseVisitor{}
+	g.indexer = &indexer{}
+	defer g.recoverError(&err)
+	g.readHeader()
+	for len(g.data) > 0 {
+		g.readContentElement()
+	}
+	g.indexer.generation(g)
+	return g.indexer.index(data, o), nil
+}
+
+// generation keeps the handle table of g, before a reset or at the end of the stream.
+func (ix *indexer) generation(g *GavaDeserilizer) {
+	ix.generations = append(ix.generations, &generation{handles: g.handles, descs: g.classDataDescriptions})
+}
+
+func (ix *indexer) index(data []byte, o DecoderOptions) *Index {
+	sort.Slice(ix.entries, func(i, j int) bool { return ix.entries[i].Span.Offset < ix.entries[j].Span.Offset })
+	end := 0
+	for i := range ix.entries {
+		e := &ix.entries[i]
+		e.TopLevel = e.Span.Offset >= end
+		if e.Span.End() > end {
+			end = e.Span.End()
+		}
+		//The values read while indexing hold no data, they are decoded again when accessed
+		ix.generations[e.Reset].handles[e.Handle-0x7e0000] = lazyHandle(i)
+	}
+	return &Index{Entries: ix.entries, data: data, opts: o, generations: ix.generations}
+}
+
+// indexed adds the value read from start with the given handle to the index being built,
+// first is the handle assigned when it started.
+func (g *GavaDeserilizer) indexed(tc byte, class string, handle, start, first int) {
+	if g.indexer == nil {
+		return
+	}
+	g.indexer.entries = append(g.indexer.entries, IndexEntry{
+		Class:  class,
+		Handle: handle,
+		Reset:  len(g.indexer.generations),
+		Span:   g.span(start),
+		tc:     tc,
+		first:  first,
+		next:   g.handleValue,
+	})
+}
+
+// Class returns the entries of the given class, arrays by their class name such as
+// "[Ljava.lang.String;".
+func (x *Index) Class(name string) []IndexEntry {
+	var entries []IndexEntry
+	for _, e := range x.Entries {
+		if e.Class == name {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// Handle returns the value with the given handle, from the part of the stream before the
+// first reset. Values after it are found in Entries.
+func (x *Index) Handle(handle int) (interface{}, error) {
+	return x.Value(IndexEntry{Handle: handle})
+}
+
+// Value returns the value of an entry, decoding it along with the values inside it. The
+// values it refers to are decoded as well, and every value is decoded once and kept.
+func (x *Index) Value(e IndexEntry) (interface{}, error) {
+	if e.Reset < 0 || e.Reset >= len(x.generations) {
+		return nil, fmt.Errorf("gava: no value with handle 0x%x", e.Handle)
+	}
+	handles := x.generations[e.Reset].handles
+	index := e.Handle - 0x7e0000
+	if index < 0 || index >= len(handles) {
+		return nil, fmt.Errorf("gava: no value with handle 0x%x", e.Handle)
+	}
+	if i, ok := handles[index].(lazyHandle); ok {
+		return x.value(int(i))
+	}
+	return handles[index], nil
+}
+
+// Get returns the value at path in the first object of the stream, see ClassDetails.Get.
+// Only the objects on the path are read, without the values they hold, and the value at
+// path is decoded.
+func (x *Index) Get(path string) (interface{}, error) {
+	segments, err := parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	var cur interface{}
+	for i, e := range x.Entries {
+		if e.TopLevel && e.tc == 0x73 {
+			cur = lazyHandle(i)
+			break
+		}
+	}
+	if cur == nil {
+		return nil, errors.New("gava: stream has no object")
+	}
+	for _, segment := range segments {
+		if cur, err = x.shallow(cur); err != nil {
+			return nil, err
+		}
+		obj, ok := cur.(*ClassDetails)
+		if !ok || obj == nil {
+			return nil, fmt.Errorf("gava: %s: %s is not an object", path, segment.name)
+		}
+		f := obj.Field(segment.name)
+		if f == nil {
+			return nil, fmt.Errorf("gava: %s: %s has no field %s", path, obj.ClassName, segment.name)
+		}
+		cur = f.Data
+		for _, index := range segment.indexes {
+			if cur, err = x.shallow(cur); err != nil {
+				return nil, err
+			}
+			array, ok := cur.(*JavaArray)
+			if !ok || array == nil {
+				return nil, fmt.Errorf("gava: %s: %s is not an array", path, segment.name)
+			}
+			i, err := strconv.Atoi(index)
+			if err != nil || i < 0 || i >= len(array.Elements) {
+				return nil, fmt.Errorf("gava: %s: invalid index %s", path, index)
+			}
+			cur = array.Elements[i]
+		}
+	}
+	if i, ok := cur.(lazyHandle); ok {
+		return x.value(int(i))
+	}
+	return cur, nil
+}
+
+// at returns the position of the entry starting at offset, or -1.
+func (x *Index) at(offset int) int {
+	i := sort.Search(len(x.Entries), func(i int) bool { return x.Entries[i].Span.Offset >= offset })
+	if i < len(x.Entries) && x.Entries[i].Span.Offset == offset {
+		return i
+	}
+	return -1
+}
+
+// decoder returns a deserializer reading the value of the entry at i, with the handle table
+// as it was when the value was written.
+func (x *Index) decoder(i int, shallow bool) *GavaDeserilizer {
+	e := x.Entries[i]
+	gen := x.generations[e.Reset]
+	g := x.opts.NewGavaDeserilizer(x.data[:e.Span.End()])
+	g.data = g.data[e.Span.Offset:]
+	//The tables are shared until the deserializer adds to them
+	n := e.first - 0x7e0000
+	g.handles = gen.handles[:n:n]
+	g.handleValue = e.first
+	g.classDataDescriptions = gen.descs[:len(gen.descs):len(gen.descs)]
+	g.lazy = x
+	g.shallow = shallow
+	return g
+}
+
+// value decodes the entry at i and keeps it with the values inside it.
+func (x *Index) value(i int) (v interface{}, err error) {
+	e := x.Entries[i]
+	handles := x.generations[e.Reset].handles
+	if _, ok := handles[e.Handle-0x7e0000].(lazyHandle); !ok {
+		return handles[e.Handle-0x7e0000], nil
+	}
+	g := x.decoder(i, false)
+	defer g.recoverError(&err)
+	g.readContentElement()
+	for h := e.first - 0x7e0000; h < len(g.handles); h++ {
+		if _, ok := handles[h].(lazyHandle); ok {
+			handles[h] = g.handles[h]
+		}
+	}
+	//A value referring back to an object holding it decodes that object first, with the value
+	return handles[e.Handle-0x7e0000], nil
+}
+
+// shallow reads the object or array of a lazyHandle without the values inside it, which are
+// left as lazyHandles. Other values are returned as they are.
+func (x *Index) shallow(v interface{}) (_ interface{}, err error) {
+	i, ok := v.(lazyHandle)
+	if !ok {
+		return v, nil
+	}
+	e := x.Entries[i]
+	if _, ok := x.generations[e.Reset].handles[e.Handle-0x7e0000].(lazyHandle); !ok || (e.tc != 0x73 && e.tc != 0x75) {
+		return x.value(int(i))
+	}
+	g := x.decoder(int(i), true)
+	defer g.recoverError(&err)
+	return g.readContentElement(), nil
+}
+
+// resolve returns the value of a lazyHandle found in the handle table.
+func (g *GavaDeserilizer) resolve(i lazyHandle) interface{} {
+	v, err := g.lazy.value(int(i))
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// skipIndexed skips a value inside the one being read when it was decoded before, and
+// returns it. Read shallowly, values not yet decoded are skipped as well and returned as
+// lazyHandles.
+func (g *GavaDeserilizer) skipIndexed() (interface{}, bool) {
+	if g.lazy == nil || g.depth == 0 {
+		return nil, false
+	}
+	g.need(1)
+	switch g.data[0] {
+	case 0x73, 0x75, 0x74, 0x7c, 0x7e:
+	default:
+		return nil, false
+	}
+	i := g.lazy.at(g.offset())
+	if i < 0 {
+		return nil, false
+	}
+	e := g.lazy.Entries[i]
+	handles := g.lazy.generations[e.Reset].handles
+	if _, ok := handles[e.Handle-0x7e0000].(lazyHandle); ok && !g.shallow {
+		return nil, false
+	}
+	g.data = g.data[e.Span.Length:]
+	g.handles = append(g.handles, handles[len(g.handles):e.next-0x7e0000]...)
+	g.handleValue = e.next
+	return handles[e.Handle-0x7e0000], true
+}
@@ -0,0 +1,176 @@
+package gava
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// UUID is the value of a java.util.UUID, most significant bits first.
+type UUID [16]byte
+
+func (u UUID) String() string {
+	s := hex.EncodeToString(u[:])
+	return s[0:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:]
+}
+
+func init() {
+	RegisterClassReader("java.util.UUID", readUUID)
+	RegisterClassReader("java.net.URI", readURI)
+	RegisterClassReader("java.net.URL", readURL)
+	RegisterClassReader("java.io.File", readFile)
+	RegisterClassReader("java.util.Locale", readLocale)
+	RegisterClassReader("java.util.Currency", readCurrency)
+	RegisterClassReader("java.net.InetAddress", readInetAddress)
+	RegisterClassReader("java.net.Inet4Address", readInetAddress)
+	RegisterClassReader("java.net.Inet6Address", readInetAddress)
+	RegisterClassReader("java.util.regex.Pattern", readPattern)
+	for _, name := range []string{"Boolean", "Byte", "Character", "Short", "Integer", "Long", "Float", "Double"} {
+		RegisterClassReader("java.lang."+name, readBoxed)
+	}
+}
+
+// readBoxed unwraps boxed primitives such as java.lang.Integer into the Go value of the primitive.
+func readBoxed(obj *ClassDetails) (interface{}, error) {
+	return fieldData(obj, "value")
+}
+
+func readUUID(obj *ClassDetails) (interface{}, error) {
+	msb, err := longField(obj, "mostSigBits")
+	if err != nil {
+		return nil, err
+	}
+	lsb, err := longField(obj, "leastSigBits")
+	if err != nil {
+		return nil, err
+	}
+	var u UUID
+	binary.BigEndian.PutUint64(u[0:8], uint64(msb))
+	binary.BigEndian.PutUint64(u[8:16], uint64(lsb))
+	return u, nil
+}
+
+func readURI(obj *ClassDetails) (interface{}, error) {
+	s, err := stringField(obj, "string")
+	if err != nil {
+		return nil, err
+	}
+	return url.Parse(s)
+}
+
+// readURL rebuilds the external form the same way URLStreamHandler.toExternalForm does.
+func readURL(obj *ClassDetails) (interface{}, error) {
+	var parts [4]string
+	for i, name := range []string{"protocol", "authority", "file", "ref"} {
+		s, err := stringField(obj, name)
+		if err != nil {
+			return nil, err
+		}
+		parts[i] = s
+	}
+	s := parts[0] + ":"
+	if parts[1] != "" {
+		s += "//" + parts[1]
+	}
+	s += parts[2]
+	if f := obj.Field("ref"); f.Data != nil {
+		s += "#" + parts[3]
+	}
+	return url.Parse(s)
+}
+
+func readFile(obj *ClassDetails) (interface{}, error) {
+	return stringField(obj, "path")
+}
+
+// readLocale returns the locale as a BCP 47 style tag such as "en-US".
+func readLocale(obj *ClassDetails) (interface{}, error) {
+	var tag []string
+	for _, name := range []string{"language", "script", "country", "variant", "extensions"} {
+		if obj.Field(name) == nil {
+			// script and extensions were added in Java 7
+			continue
+		}
+		s, err := stringField(obj, name)
+		if err != nil {
+			return nil, err
+		}
+		if name == "language" && s == "" {
+			s = "und"
+		}
+		if s != "" {
+			tag = append(tag, strings.Replace(s, "_", "-", -1))
+		}
+	}
+	return strings.Join(tag, "-"), nil
+}
+
+func readCurrency(obj *ClassDetails) (interface{}, error) {
+	return stringField(obj, "currencyCode")
+}
+
+// readInetAddress reads both the InetAddress holder fields and the Inet6Address address bytes.
+func readInetAddress(obj *ClassDetails) (interface{}, error) {
+	if f := obj.Field("ipaddress"); f != nil && f.Data != nil {
+		b, err := byteArray(f.Data)
+		if err != nil {
+			return nil, err
+		}
+		if len(b) != net.IPv6len {
+			return nil, fmt.Errorf("invalid IPv6 address length %d", len(b))
+		}
+		return net.IP(b), nil
+	}
+	family, err := intField(obj, "family")
+	if err != nil {
+		return nil, err
+	}
+	if family != 1 {
+		return nil, fmt.Errorf("unsupported address family %d", family)
+	}
+	address, err := intField(obj, "address")
+	if err != nil {
+		return nil, err
+	}
+	ip := make(net.IP, net.IPv4len)
+	binary.BigEndian.PutUint32(ip, uint32(address))
+	return ip, nil
+}
+
+// readPattern compiles the pattern as a Go regexp, patterns using syntax RE2 does not
+// support, or flags with no RE2 equivalent, are left undecoded.
+func readPattern(obj *ClassDetails) (interface{}, error) {
+	pattern, err := stringField(obj, "pattern")
+	if err != nil {
+		return nil, err
+	}
+	flags, err := intField(obj, "flags")
+	if err != nil {
+		return nil, err
+	}
+	if flags&(0x04|0x10) != 0 { //COMMENTS, LITERAL
+		return nil, nil
+	}
+	var prefix string
+	if flags&0x02 != 0 { //CASE_INSENSITIVE
+		prefix += "i"
+	}
+	if flags&0x08 != 0 { //MULTILINE
+		prefix += "m"
+	}
+	if flags&0x20 != 0 { //DOTALL
+		prefix += "s"
+	}
+	if prefix != "" {
+		pattern = "(?" + prefix + ")" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, nil
+	}
+	return re, nil
+}
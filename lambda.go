@@ -0,0 +1,53 @@
+package gava
+
+// Lambda is a serializable lambda or method reference, written as a
+// java.lang.invoke.SerializedLambda.
+type Lambda struct {
+	CapturingClass                     string
+	FunctionalInterfaceClass           string
+	FunctionalInterfaceMethodName      string
+	FunctionalInterfaceMethodSignature string
+	ImplClass                          string
+	ImplMethodName                     string
+	ImplMethodKind                     int32
+	Signature                          string
+	InstantiatedMethodType             string
+	CapturedArgs                       []interface{}
+}
+
+func init() {
+	RegisterClassReader("java.lang.invoke.SerializedLambda", readSerializedLambda)
+}
+
+// readSerializedLambda reads the SerializedLambda fields, the class names other than the
+// capturing class are internal names such as "com/acme/Orders".
+func readSerializedLambda(obj *ClassDetails) (interface{}, error) {
+	l := &Lambda{}
+	capturingClass, err := fieldData(obj, "capturingClass")
+	if err != nil {
+		return nil, err
+	}
+	if cdd, ok := capturingClass.(*ClassDataDesc); ok {
+		l.CapturingClass = cdd.ClassDetail[0].ClassName
+	}
+	for name, s := range map[string]*string{
+		"functionalInterfaceClass":           &l.FunctionalInterfaceClass,
+		"functionalInterfaceMethodName":      &l.FunctionalInterfaceMethodName,
+		"functionalInterfaceMethodSignature": &l.FunctionalInterfaceMethodSignature,
+		"implClass":                          &l.ImplClass,
+		"implMethodName":                     &l.ImplMethodName,
+		"implMethodSignature":                &l.Signature,
+		"instantiatedMethodType":             &l.InstantiatedMethodType,
+	} {
+		if *s, err = stringField(obj, name); err != nil {
+			return nil, err
+		}
+	}
+	if l.ImplMethodKind, err = intField(obj, "implMethodKind"); err != nil {
+		return nil, err
+	}
+	if l.CapturedArgs, err = sliceField(obj, "capturedArgs"); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
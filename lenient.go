@@ -0,0 +1,129 @@
+package gava
+
+import (
+	"bytes"
+	"errors"
+	"io"
+)
+
+// DiagnosticSeverity tells what became of the stream around a problem found in lenient mode.
+type DiagnosticSeverity string
+
+const (
+	// DiagnosticWarning is a problem parsing went on after, reading the stream as written or
+	// with a nil value.
+	DiagnosticWarning DiagnosticSeverity = "warning"
+	// DiagnosticError is a problem parsing resumed after by skipping bytes.
+	DiagnosticError DiagnosticSeverity = "error"
+)
+
+// Diagnostic is a problem found parsing a stream in lenient mode.
+type Diagnostic struct {
+	Offset   int
+	Severity DiagnosticSeverity
+	Err      error
+	// Skipped is the number of bytes skipped to resume parsing.
+	Skipped int
+}
+
+func (d Diagnostic) String() string {
+	return string(d.Severity) + ": " + (&ParseError{Offset: d.Offset, Err: d.Err}).Error()
+}
+
+// stopParsing ends a lenient parse that can't resume.
+type stopParsing struct{}
+
+// problem fails like fail, or records a warning and returns in lenient mode.
+func (g *GavaDeserilizer) problem(msg string) {
+	if !g.lenient {
+		g.fail(msg)
+	}
+	g.diagnostics = append(g.diagnostics, Diagnostic{Offset: g.offset(), Severity: DiagnosticWarning, Err: errors.New(msg)})
+}
+
+// readAnnotations reads the content elements of class and object annotations and their
+// spans, up to and including the TC_ENDBLOCKDATA ending them.
+func (g *GavaDeserilizer) readAnnotations() ([]interface{}, []Span) {
+	var values []interface{}
+	var spans []Span
+	for {
+		g.need(1)
+		if g.data[0] == 0x78 {
+			break
+		}
+		g.readElement(&values, &spans, 0x78)
+	}
+	g.data = g.data[1:]
+	return values, spans
+}
+
+// readElement appends the next content element to values and its span to spans. In lenient
+// mode a failure is recorded, the object being read when it happened, if any, is appended
+// with the fields read so far, and parsing resumes at the next resync byte. Limits and
+// filters still fail.
+func (g *GavaDeserilizer) readElement(values *[]interface{}, spans *[]Span, resync byte) {
+	start := g.offset()
+	if !g.lenient {
+		*values = append(*values, g.readContentElement())
+		*spans = append(*spans, g.span(start))
+		return
+	}
+	building := len(g.building)
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+		if len(g.building) > building {
+			*values = append(*values, g.building[building])
+			*spans = append(*spans, g.span(start))
+			g.building = g.building[:building]
+		}
+		if _, ok := r.(stopParsing); ok {
+			panic(r)
+		}
+		err := g.parseError(r)
+		var filtered *FilterError
+		if errors.As(err, &filtered) || isLimit(err.Err) {
+			panic(r)
+		}
+		i := bytes.IndexByte(g.data, resync)
+		if errors.Is(err.Err, io.ErrUnexpectedEOF) || i < 0 {
+			i = len(g.data)
+		}
+		g.diagnostics = append(g.diagnostics, Diagnostic{Offset: err.Offset, Severity: DiagnosticError, Err: err.Err, Skipped: i})
+		g.data = g.data[i:]
+		if len(g.data) == 0 {
+			panic(stopParsing{})
+		}
+	}()
+	*values = append(*values, g.readContentElement())
+	*spans = append(*spans, g.span(start))
+}
+
+func isLimit(err error) bool {
+	for _, limit := range []error{ErrMaxDepth, ErrMaxArrayLength, ErrMaxStringLength, ErrMaxTotalBytes, ErrMaxObjects, ErrMaxReferences} {
+		if err == limit {
+			return true
+		}
+	}
+	return false
+}
+
+// readContents reads the top-level content elements of a document, resuming at the next
+// TC_RESET after a failure in lenient mode.
+func (g *GavaDeserilizer) readContents(doc *Document) {
+	if g.lenient {
+		defer func() {
+			if r := recover(); r != nil {
+				if _, ok := r.(stopParsing); !ok {
+					panic(r)
+				}
+			}
+			doc.Diagnostics = g.diagnostics
+		}()
+	}
+	for len(g.data) > 0 {
+		g.readElement(&doc.Contents, &doc.ContentSpans, 0x79)
+	}
+}
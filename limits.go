@@ -0,0 +1,106 @@
+package gava
+
+import "errors"
+
+// Errors returned, wrapped in a *ParseError, when a stream exceeds a limit of DecoderOptions.
+var (
+	ErrMaxDepth        = errors.New("gava: stream nested too deeply")
+	ErrMaxArrayLength  = errors.New("gava: array too long")
+	ErrMaxStringLength = errors.New("gava: string too long")
+	ErrMaxTotalBytes   = errors.New("gava: stream too large")
+	ErrMaxObjects      = errors.New("gava: too many objects")
+	ErrMaxReferences   = errors.New("gava: too many references")
+	ErrMaxValueLength  = errors.New("gava: value too long")
+)
+
+// DecoderOptions limits the resources decoding a stream may use, for streams from untrusted
+// sources. A limit of 0 means no limit. MaxObjects and MaxDepth bound the values made from
+// the stream, but not ClassField.Value: the Value of an object that isn't decoded is its
+// JSON, the Values of its own fields escaped inside, so it doubles in size with every level
+// of nesting, and a stream of a few hundred bytes can make values of megabytes. Only
+// MaxValueLength bounds the memory and time spent on them.
+type DecoderOptions struct {
+	// MaxDepth limits the nesting of objects, arrays and class descriptors, super classes
+	// included.
+	MaxDepth int
+	// MaxArrayLength limits the number of elements of an array.
+	MaxArrayLength int
+	// MaxStringLength limits the length in bytes of strings, class names and field names.
+	MaxStringLength int
+	// MaxTotalBytes limits the size of the stream.
+	MaxTotalBytes int
+	// MaxObjects limits the number of objects, arrays, enums, strings and class descriptors,
+	// counted across resets.
+	MaxObjects int
+	// MaxReferences limits the number of references to values read before.
+	MaxReferences int
+	// MaxValueLength limits the length in bytes of ClassField.Value and
+	// ClassDetails.ObjectValue.
+	MaxValueLength int
+	// Filter is called for every new class descriptor, array and reference, see InputFilter.
+	Filter InputFilter
+	// NestedDepth makes ParseDocument decode the streams found in byte arrays and block
+	// data into Document.Nested, up to this many levels of nesting.
+	NestedDepth int
+	// Lenient makes ParseDocument record problems in Document.Diagnostics and keep going
+	// instead of failing, for damaged streams. Illegal class descriptor flags, field type
+	// codes and handles are recorded as warnings. After other problems the rest of the
+	// annotation, or of the top level up to the next TC_RESET, is skipped, and the object
+	// being read is kept with the fields read so far. Limits and filters still fail, and
+	// Decode and Unmarshal aren't lenient.
+	Lenient bool
+}
+
+// NewGavaDeserilizer returns a deserializer of data that fails with the errors of the limits
+// when data exceeds them.
+func (o DecoderOptions) NewGavaDeserilizer(data []byte) *GavaDeserilizer {
+	g := NewGavaDeserilizer(data)
+	g.opts = o
+	return g
+}
+
+// Decode returns the first object of the stream in data, see GavaDeserilizer.Decode.
+func (o DecoderOptions) Decode(data []byte) (*ClassDetails, error) {
+	return o.NewGavaDeserilizer(data).Decode()
+}
+
+// ParseDocument parses every content element of the stream in data, see ParseDocument.
+func (o DecoderOptions) ParseDocument(data []byte) (*Document, error) {
+	return o.NewGavaDeserilizer(data).decodeDocument()
+}
+
+func (g *GavaDeserilizer) checkTotalBytes() {
+	if g.opts.MaxTotalBytes > 0 && g.size > g.opts.MaxTotalBytes {
+		g.failWith(ErrMaxTotalBytes)
+	}
+}
+
+// enter is called when a nested object, array or class descriptor starts, and leave when it ends.
+func (g *GavaDeserilizer) enter() {
+	g.depth++
+	if g.opts.MaxDepth > 0 && g.depth > g.opts.MaxDepth {
+		g.failWith(ErrMaxDepth)
+	}
+}
+
+func (g *GavaDeserilizer) leave() {
+	g.depth--
+}
+
+func (g *GavaDeserilizer) checkArrayLength(n int) {
+	if g.opts.MaxArrayLength > 0 && n > g.opts.MaxArrayLength {
+		g.failWith(ErrMaxArrayLength)
+	}
+}
+
+func (g *GavaDeserilizer) checkValueLength(n int) {
+	if g.opts.MaxValueLength > 0 && n > g.opts.MaxValueLength {
+		g.failWith(ErrMaxValueLength)
+	}
+}
+
+func (g *GavaDeserilizer) checkStringLength(n uint64) {
+	if g.opts.MaxStringLength > 0 && n > uint64(g.opts.MaxStringLength) {
+		g.failWith(ErrMaxStringLength)
+	}
+}
@@ -3,33 +3,123 @@ package gava
 import (
 	"encoding/binary"
 	"encoding/hex"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"math"
+	"strings"
 )
 
 type GavaDeserilizer struct {
 	handleValue           int
 	classDataDescriptions []*ClassDataDesc
+	handles               []interface{}
+	exception             *JavaException
+	size                  int
 	data                  []byte
+	opts                  DecoderOptions
+	depth                 int
+	objects               int
+	references            int
+	nesting               int
+	// lenient records problems in diagnostics and keeps going, see DecoderOptions.Lenient
+	lenient     bool
+	validating  bool
+	diagnostics []Diagnostic
+	// building are the objects being read, innermost last
+	building []*ClassDetails
+	// visitor gets the events of the stream instead of values being built, see Visit
+	visitor  Visitor
+	skipping int
+	// descriptorRefs are the descriptors of classDataDescriptions by handle, up to indexedDescs
+	descriptorRefs map[int]*ClassDataDesc
+	indexedDescs   int
+	// indexer collects the values of a stream for NewIndex, lazy decodes the values of an
+	// Index, without the values inside them when shallow
+	indexer *indexer
+	lazy    *Index
+	shallow bool
+	// noValues leaves ClassField.Value and ClassDetails.ObjectValue empty, for streams that
+	// are only written back
+	noValues bool
 }
 
 func NewGavaDeserilizer(data []byte) *GavaDeserilizer {
 	return &GavaDeserilizer{
 		handleValue:           0x7e0000,
 		classDataDescriptions: []*ClassDataDesc{},
+		handles:               []interface{}{},
+		size:                  len(data),
 		data:                  data,
 	}
 }
 
+// Parse returns the first object of the stream, it exits the program when the stream is invalid.
 func (g *GavaDeserilizer) Parse() *ClassDetails {
+	obj, err := g.Decode()
+	if errors.Is(err, ErrStreamMagic) {
+		return nil
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	return obj
+}
+
+// Decode returns the first object of the stream like Parse, or a *ParseError when the stream is invalid.
+func (g *GavaDeserilizer) Decode() (*ClassDetails, error) {
+	c, err := g.decodeContent()
+	obj, _ := c.(*ClassDetails)
+	return obj, err
+}
+
+// decodeContent reads the stream header and returns the first content element.
+func (g *GavaDeserilizer) decodeContent() (content interface{}, err error) {
+	defer g.recoverError(&err)
+
+	_, returnData := g.readHeader()
+
+	//ReturnData starts with a block holding the return type and the UID of the call
+	var returnType byte
+	if returnData {
+		b, ok := g.readContentElement().(BlockData)
+		if !ok || len(b) == 0 {
+			g.fail("Error: Missing RMI return type.")
+		}
+		returnType = b[0]
+	}
+
+	//fmt.Println("Contents")
+	for len(g.data) > 0 {
+		c := g.readContentElement()
+		if _, ok := c.(Reset); ok {
+			continue
+		}
+		if obj, ok := c.(*ClassDetails); ok && returnType == 0x02 { //ExceptionalReturn
+			if e, ok := obj.Decoded.(*JavaException); ok {
+				g.exception = e
+			}
+		}
+		return c, nil
+	}
+
+	return nil, nil
+}
+
+// readHeader reads the optional RMI packet type byte, the stream magic and the version.
+func (g *GavaDeserilizer) readHeader() (packet byte, returnData bool) {
 	var b1 byte
 	var b2 byte
 
+	g.checkTotalBytes()
+
 	//The stream may begin with an RMI packet type byte, print it if so
+	g.need(1)
 	if g.data[0] != 0xac {
 		b1 = g.data[0]
 		g.data = g.data[1:]
+		packet = b1
 
 		switch b1 {
 		case 0x50:
@@ -37,6 +127,7 @@ func (g *GavaDeserilizer) Parse() *ClassDetails {
 			break
 		case 0x51:
 			//fmt.Println("RMI ReturnData - 0x51")
+			returnData = true
 			break
 		case 0x52:
 			//fmt.Println("RMI Ping - 0x52")
@@ -54,6 +145,7 @@ func (g *GavaDeserilizer) Parse() *ClassDetails {
 	}
 
 	//Magic number, print and validate
+	g.need(2)
 	b1 = g.data[0]
 	g.data = g.data[1:]
 	b2 = g.data[0]
@@ -62,10 +154,11 @@ func (g *GavaDeserilizer) Parse() *ClassDetails {
 	//fmt.Println("STREAM_MAGIC - 0x" + hex.EncodeToString([]byte{b1}) + " " + hex.EncodeToString([]byte{b2}))
 	if b1 != 0xac || b2 != 0xed {
 		//fmt.Println("Invalid STREAM_MAGIC, should be 0xac ed")
-		return nil
+		g.failWith(ErrStreamMagic)
 	}
 
 	//Serialization version
+	g.need(2)
 	b1 = g.data[0]
 	g.data = g.data[1:]
 	b2 = g.data[0]
@@ -75,151 +168,315 @@ func (g *GavaDeserilizer) Parse() *ClassDetails {
 	if b1 != 0x00 || b2 != 0x05 {
 		//fmt.Println("Invalid STREAM_VERSION, should be 0x00 05")
 	}
+	return packet, returnData
+}
 
-	//fmt.Println("Contents")
-	for len(g.data) > 0 {
-		c, _ := g.readContentElement()
-		return c
+// Exception returns the exception of an RMI ReturnData holding an exceptional return, or
+// the one written by TC_EXCEPTION when the stream was aborted, and nil otherwise.
+func (g *GavaDeserilizer) Exception() error {
+	if g.exception == nil {
+		return nil
 	}
-
-	return nil
+	return g.exception
 }
 
-func (g *GavaDeserilizer) readContentElement() (*ClassDetails, string) {
+func (g *GavaDeserilizer) readContentElement() interface{} {
+	g.nesting++
+	defer func() { g.nesting-- }()
+	if v, ok := g.skipIndexed(); ok {
+		return v
+	}
+	g.need(1)
 	switch g.data[0] {
 	case 0x73: //TC_OBJECT
-		return g.readNewObject(), ""
+		return g.readNewObject()
 	case 0x76: //TC_CLASS
-		g.readNewClass()
-		break
+		c := g.readNewClass()
+		g.value(c)
+		return c
 	case 0x75: //TC_ARRAY
-		return nil, g.readNewArray()
+		return g.readNewArray()
 	case 0x74: //TC_STRING
 		fallthrough
 	case 0x7c: //TC_LONGSTRING
-		return nil, g.readNewString()
+		str := g.readNewString()
+		g.value(str)
+		return str
 	case 0x7e: //TC_ENUM
-		return nil, g.readNewEnum()
+		return g.readNewEnum()
 	case 0x72: //TC_CLASSDESC
 		fallthrough
 	case 0x7d: //TC_PROXYCLASSDESC
-		g.readNewClassDesc()
-		break
+		cdd := g.readNewClassDesc()
+		cdd.descriptor = true
+		return cdd
 	case 0x71: //TC_REFERENCE
-		g.readPrevObject()
-		break
+		return g.readReference()
 	case 0x70: //TC_NULL
 		g.readNullReference()
-		break
-		//			case 0x7b:		//TC_EXCEPTION
-		//				readException()
-		//				break
-		//			case 0x79:		//TC_RESET
-		//				handleReset()
-		//				break
+		g.value(nil)
+		return nil
+	case 0x7b: //TC_EXCEPTION
+		return g.readException()
+	case 0x79: //TC_RESET
+		g.data = g.data[1:]
+		g.handleReset()
+		g.event(func(v Visitor) { v.Reset() })
+		return Reset{}
 	case 0x77: //TC_BLOCKDATA
-		return nil, g.readBlockData()
+		b := g.readBlockData()
+		g.event(func(v Visitor) { v.BlockData(b) })
+		return b
 	case 0x7a: //TC_BLOCKDATALONG
-		return nil, g.readLongBlockData()
+		b := g.readLongBlockData()
+		g.event(func(v Visitor) { v.BlockData(b) })
+		return b
 	default:
-		print("Invalid content element type 0x" + hex.EncodeToString([]byte{g.data[0]}))
-		log.Fatal("Error: Illegal content element type.")
+		g.fail("Error: Illegal content element type 0x" + hex.EncodeToString([]byte{g.data[0]}) + ".")
+	}
+	return nil
+}
+
+func (g *GavaDeserilizer) readException() *ClassDetails {
+	g.need(1)
+	var b1 = g.data[0]
+	g.data = g.data[1:]
+	//fmt.Println("TC_EXCEPTION - 0x", hex.EncodeToString([]byte{b1}))
+	if b1 != 0x7b {
+		g.fail("b1 != 0x7b")
+	}
+
+	//The exception is written between two resets of the handle table
+	g.handleReset()
+	g.event(func(v Visitor) { v.Exception() })
+	obj, ok := g.readContentElement().(*ClassDetails)
+	if !ok || obj == nil {
+		g.fail("Error: TC_EXCEPTION is not followed by an object.")
+	}
+	g.handleReset()
+
+	if e, ok := obj.Decoded.(*JavaException); ok {
+		g.exception = e
+	}
+	return obj
+}
+
+func (g *GavaDeserilizer) handleReset() {
+	if g.indexer != nil {
+		g.indexer.generation(g)
+	}
+	g.handleValue = 0x7e0000
+	g.handles = []interface{}{}
+	g.classDataDescriptions = []*ClassDataDesc{}
+	g.descriptorRefs, g.indexedDescs = nil, 0
+}
+
+// descriptorRef returns the class data description of the descriptor with the given handle
+// and its super classes, or nil. Descriptions are built once per handle and shared by the
+// values referring to them.
+func (g *GavaDeserilizer) descriptorRef(handle int) *ClassDataDesc {
+	if cdd, ok := g.descriptorRefs[handle]; ok {
+		return cdd
+	}
+	if g.descriptorRefs == nil {
+		g.descriptorRefs = map[int]*ClassDataDesc{}
 	}
-	return nil, ""
+	//Descriptors are indexed as they are looked up, from the last one indexed
+	for ; g.indexedDescs < len(g.classDataDescriptions); g.indexedDescs++ {
+		cdd := g.classDataDescriptions[g.indexedDescs]
+		for classIndex, cd := range cdd.ClassDetail {
+			if _, ok := g.descriptorRefs[cd.RefHandle]; !ok {
+				g.descriptorRefs[cd.RefHandle] = cdd.buildClassDataDescFromIndex(classIndex)
+			}
+		}
+	}
+	return g.descriptorRefs[handle]
 }
 
-func (g *GavaDeserilizer) readBlockData() string {
+func (g *GavaDeserilizer) readBlockData() BlockData {
+	start := g.offset()
+	g.need(1)
 	var b1 = g.data[0]
 	g.data = g.data[1:]
 	//fmt.Println("TC_BLOCK_DATA - 0x", hex.EncodeToString([]byte{b1}))
 	if b1 != 0x77 {
-		log.Fatal("b1 != 0x77")
+		g.fail("b1 != 0x77")
 	}
+	g.need(1)
 	var len = g.data[0] & 0xFF
 	g.data = g.data[1:]
+	if len == 0 {
+		g.nonConforming(start, "empty TC_BLOCKDATA")
+	}
 
 	//fmt.Println(fmt.Sprintf("Length - %d", len))
 
-	var contents = ""
-
-	for i := byte(0); i < len; i++ {
-		contents += hex.EncodeToString([]byte{g.data[0]})
-		g.data = g.data[1:]
-	}
-	//fmt.Println(fmt.Sprintf("Contents - 0x%s", contents))
-	return contents
+	return g.readBlockContents(int(len))
 }
 
-func (g *GavaDeserilizer) readLongBlockData() string {
+func (g *GavaDeserilizer) readLongBlockData() BlockData {
+	start := g.offset()
+	g.need(1)
 	var b1 = g.data[0]
 	g.data = g.data[1:]
 	//fmt.Println("TC_BLOCK_DATA_LONG - 0x", hex.EncodeToString([]byte{b1}))
 	if b1 != 0x7a {
-		log.Fatal("b1 != 0x7a")
+		g.fail("b1 != 0x7a")
 	}
 
+	g.need(4)
 	var len = int(binary.BigEndian.Uint32(g.data[0:4]))
 	g.data = g.data[4:]
+	if len <= 0xff {
+		g.nonConforming(start, fmt.Sprintf("TC_BLOCKDATALONG of %d bytes, TC_BLOCKDATA holds up to 255", len))
+	}
 	//fmt.Println(fmt.Sprintf("Length - %d", len))
 
-	var contents = ""
+	return g.readBlockContents(len)
+}
 
-	for i := 0; i < len; i++ {
-		contents += hex.EncodeToString([]byte{g.data[0]})
-		g.data = g.data[1:]
+// readBlockContents returns the next n bytes of the stream, sharing its storage.
+func (g *GavaDeserilizer) readBlockContents(n int) BlockData {
+	if n < 0 || n > len(g.data) {
+		g.failWith(io.ErrUnexpectedEOF)
 	}
-
-	//fmt.Println(fmt.Sprintf("Contents - 0x%s", contents))
+	contents := BlockData(g.data[:n:n])
+	g.data = g.data[n:]
+	//fmt.Println(fmt.Sprintf("Contents - 0x%s", hex.EncodeToString(contents)))
 	return contents
 }
 
 func (g *GavaDeserilizer) readNullReference() string {
+	g.need(1)
 	var b1 = g.data[0]
 	g.data = g.data[1:]
 	//fmt.Println("TC_NULL - 0x" + hex.EncodeToString([]byte{b1}))
 	if b1 != 0x70 {
-		log.Fatal("Error: Illegal value for TC_NULL (should be 0x70)")
+		g.fail("Error: Illegal value for TC_NULL (should be 0x70)")
 	}
 	return "null"
 }
 
 func (g *GavaDeserilizer) readPrevObject() int {
+	g.need(1)
 	var b1 = g.data[0]
 	g.data = g.data[1:]
 
 	//fmt.Println("TC_REFERENCE - 0x" + hex.EncodeToString([]byte{b1}))
 
 	if b1 != 0x71 {
-		log.Fatal("b1 != 0x71")
+		g.fail("b1 != 0x71")
 	}
 
+	g.need(4)
 	handle := int(binary.BigEndian.Uint32(g.data[0:4]))
 	g.data = g.data[4:]
 	//fmt.Println(fmt.Sprintf("Handle - %d", handle))
+	g.references++
+	if g.opts.MaxReferences > 0 && g.references > g.opts.MaxReferences {
+		g.failWith(ErrMaxReferences)
+	}
+	g.filter("", -1)
+	return handle
+}
+
+// readReference reads a TC_REFERENCE to a value and returns the value.
+func (g *GavaDeserilizer) readReference() interface{} {
+	handle := g.readPrevObject()
+	g.event(func(v Visitor) { v.Reference(handle) })
+	//Values read shallowly keep the values they refer to as lazyHandles
+	if index := handle - 0x7e0000; g.shallow && index >= 0 && index < len(g.handles) {
+		if v, ok := g.handles[index].(lazyHandle); ok {
+			return v
+		}
+	}
+	return g.lookupHandle(handle)
+}
+
+// newHandle assigns the next wire handle to obj so that later TC_REFERENCEs can resolve to it.
+func (g *GavaDeserilizer) newHandle(obj interface{}) int {
+	g.objects++
+	if g.opts.MaxObjects > 0 && g.objects > g.opts.MaxObjects {
+		g.failWith(ErrMaxObjects)
+	}
+	handle := g.handleValue
+	g.handles = append(g.handles, obj)
+	g.handleValue++
 	return handle
 }
 
+func (g *GavaDeserilizer) lookupHandle(handle int) interface{} {
+	index := handle - 0x7e0000
+	if index < 0 || index >= len(g.handles) {
+		g.problem(fmt.Sprintf("Error: Invalid reference handle (0x%x)", handle))
+		return nil
+	}
+	if i, ok := g.handles[index].(lazyHandle); ok {
+		g.handles[index] = g.resolve(i)
+	}
+	return g.handles[index]
+}
+
 func (g *GavaDeserilizer) readNewClassDesc() *ClassDataDesc {
+	g.need(1)
 	switch g.data[0] {
 	case 0x72:
 		cdd := g.readTCClassDesc()
 		g.classDataDescriptions = append(g.classDataDescriptions, cdd)
 		return cdd
 	case 0x7d:
-		return g.readTCProxyClassDesc()
+		cdd := g.readTCProxyClassDesc()
+		g.classDataDescriptions = append(g.classDataDescriptions, cdd)
+		return cdd
 	default:
 		// print("Invalid newClassDesc type 0x" + this.byteToHex(this._data.peek()));
-		log.Fatal("Error illegal newClassDesc type.")
+		g.fail("Error illegal newClassDesc type.")
 	}
 	return nil
 }
 
+// readTCProxyClassDesc reads the descriptor of a dynamic proxy class: its interfaces, class
+// annotations and super class, java.lang.reflect.Proxy. The proxy class itself has no data.
 func (g *GavaDeserilizer) readTCProxyClassDesc() *ClassDataDesc {
-	return nil
+	g.enter()
+	defer g.leave()
+	start := g.offset()
+	g.data = g.data[1:]
+
+	desc := &ClassDetails{ClassDescFlags: 0x02, ProxyInterfaces: []string{}}
+	cdd := &ClassDataDesc{ClassDetail: []*ClassDetails{desc}}
+	desc.RefHandle = g.newHandle(desc)
+	defer func() { desc.Span = g.span(start) }()
+
+	g.need(4)
+	count := int(binary.BigEndian.Uint32(g.data[0:4]))
+	g.data = g.data[4:]
+	//Every interface name takes at least 2 bytes
+	if count > len(g.data)/2 {
+		g.failWith(io.ErrUnexpectedEOF)
+	}
+	for i := 0; i < count; i++ {
+		name, raw := g.readName()
+		g.filter(name, -1)
+		desc.ProxyInterfaces = append(desc.ProxyInterfaces, name)
+		if raw != nil {
+			if desc.rawInterfaces == nil {
+				desc.rawInterfaces = make([][]byte, count)
+			}
+			desc.rawInterfaces[i] = raw
+		}
+	}
+
+	g.readClassAnnotation(desc)
+	if scdd := g.readSuperClassDesc(); scdd != nil {
+		cdd.ClassDetail = append(cdd.ClassDetail, scdd.ClassDetail...)
+	}
+	return cdd
 }
 
 func (g *GavaDeserilizer) readClassDescInfo(cdd *ClassDataDesc) {
 	var classDescFlags string
+	g.need(1)
 	var b1 = g.data[0]
 	g.data = g.data[1:]
 
@@ -246,19 +503,25 @@ func (g *GavaDeserilizer) readClassDescInfo(cdd *ClassDataDesc) {
 
 	//Validate classDescFlags
 	if (b1 & 0x02) == 0x02 {
-		if (b1 & 0x04) == 0x04 { log.Fatal("Error: Illegal classDescFlags, SC_SERIALIZABLE is not compatible with SC_EXTERNALIZABLE."); }
-		if (b1 & 0x08) == 0x08 { log.Fatal("Error: Illegal classDescFlags, SC_SERIALIZABLE is not compatible with SC_BLOCKDATA."); }
+		if (b1 & 0x04) == 0x04 {
+			g.problem("Error: Illegal classDescFlags, SC_SERIALIZABLE is not compatible with SC_EXTERNALIZABLE.")
+		}
+		if (b1 & 0x08) == 0x08 {
+			g.problem("Error: Illegal classDescFlags, SC_SERIALIZABLE is not compatible with SC_BLOCKDATA.")
+		}
 	} else if (b1 & 0x04) == 0x04 {
-		if (b1 & 0x01) == 0x01 { log.Fatal("Error: Illegal classDescFlags, SC_EXTERNALIZABLE is not compatible with SC_WRITE_METHOD."); }
+		if (b1 & 0x01) == 0x01 {
+			g.problem("Error: Illegal classDescFlags, SC_EXTERNALIZABLE is not compatible with SC_WRITE_METHOD.")
+		}
 	} else if b1 != 0x00 {
-		log.Fatal("Error: Illegal classDescFlags, must include either SC_SERIALIZABLE or SC_EXTERNALIZABLE.");
+		g.problem("Error: Illegal classDescFlags, must include either SC_SERIALIZABLE or SC_EXTERNALIZABLE.")
 	}
 	//
 	//fields
 	g.readFields(cdd) //Read field descriptions and add them to the ClassDataDesc
 	//
 	//classAnnotation
-	g.readClassAnnotation()
+	g.readClassAnnotation(cdd.ClassDetail[len(cdd.ClassDetail)-1])
 	//
 	//superClassDesc
 	scdd := g.readSuperClassDesc() //Read the super class description and add it to the ClassDataDesc
@@ -269,12 +532,9 @@ func (g *GavaDeserilizer) readClassDescInfo(cdd *ClassDataDesc) {
 	}
 }
 
-func (g *GavaDeserilizer) readClassAnnotation() {
+func (g *GavaDeserilizer) readClassAnnotation(cd *ClassDetails) {
 	//fmt.Println("classAnnotations")
-	for g.data[0] != 0x78 {
-		g.readContentElement()
-	}
-	g.data = g.data[1:]
+	cd.Annotation, cd.AnnotationSpans = g.readAnnotations()
 	//fmt.Println("TC_END_BLOCK_DATA - 0x78")
 }
 
@@ -289,6 +549,7 @@ func (g *GavaDeserilizer) readFields(cdd *ClassDataDesc) {
 	var b2 byte
 	var count uint16
 
+	g.need(2)
 	b1 = g.data[0]
 	g.data = g.data[1:]
 	b2 = g.data[0]
@@ -312,6 +573,8 @@ func (g *GavaDeserilizer) readFields(cdd *ClassDataDesc) {
 }
 
 func (g *GavaDeserilizer) readFieldDesc(cdd *ClassDataDesc) {
+	start := g.offset()
+	g.need(1)
 	var b1 = g.data[0]
 	g.data = g.data[1:]
 
@@ -340,49 +603,58 @@ func (g *GavaDeserilizer) readFieldDesc(cdd *ClassDataDesc) {
 	case 'L':
 		//fmt.Println("Object")
 	default:
-		log.Fatal("Error: Illegal field type code ('" + string(b1) + "', 0x" + hex.EncodeToString([]byte{b1}) + ")")
+		g.problem("Error: Illegal field type code ('" + string(b1) + "', 0x" + hex.EncodeToString([]byte{b1}) + ")")
 	}
 
 	//fmt.Println("fieldName")
 
-	fieldName := g.readUtf()
-	field.Name = fieldName
+	field.Name, field.rawName = g.readName()
 
 	if b1 == '[' || b1 == 'L' {
 		//fmt.Println("className1")
-		field.className = g.readNewString()
+		field.typeString = g.readNewString()
+		field.className = field.typeString.Value
 	}
+	field.Span = g.span(start)
 }
 
-func (g *GavaDeserilizer) readUtf() string {
-	content := ""
-	hexStr := ""
+// readName reads a class or field name, with its bytes when they aren't the ones Java writes.
+func (g *GavaDeserilizer) readName() (string, []byte) {
+	b := g.readUtfBytes()
+	name := decodeModifiedUTF8(b)
+	return name, rawUTF(b, name)
+}
+
+// readUtfBytes returns the modified UTF-8 bytes of a string with a 2 byte length.
+func (g *GavaDeserilizer) readUtfBytes() []byte {
 	var b1 byte
 	var b2 byte
 	var len int
 
 	//length
+	g.need(2)
 	b1 = g.data[0]
 	g.data = g.data[1:]
 	b2 = g.data[0]
 	g.data = g.data[1:]
-	len = (int(b1<<8) & 0xff00) + int(b2&0xff)
+	len = int(b1)<<8 | int(b2)
+	g.checkStringLength(uint64(len))
 
 	//fmt.Println("Length - " + string(len) + " - 0x" + hex.EncodeToString([]byte{b1}) + " " + hex.EncodeToString([]byte{b2}))
 
-	//Contents
-	for i := 0; i < len; i++ {
-		b1 = g.data[0]
-		g.data = g.data[1:]
-		content += string(b1)
-		hexStr += hex.EncodeToString([]byte{b1})
-	}
-	//fmt.Println("Value - " + content + " - 0x" + hexStr)
+	//Contents, in modified UTF-8
+	g.need(len)
+	content := g.data[0:len]
+	g.data = g.data[len:]
+	//fmt.Println("Value - 0x" + hex.EncodeToString(content))
 
 	return content
 }
 
 func (g *GavaDeserilizer) readTCClassDesc() *ClassDataDesc {
+	g.enter()
+	defer g.leave()
+	start := g.offset()
 	var cdd = &ClassDataDesc{}
 	// var b1 = g.data[0]
 	g.data = g.data[1:]
@@ -391,349 +663,486 @@ func (g *GavaDeserilizer) readTCClassDesc() *ClassDataDesc {
 	// if(b1 != (byte)0x72) { throw new RuntimeException("Error: Illegal value for TC_CLASSDESC (should be 0x72)"); }
 	//fmt.Println("className")
 
-	className := g.readUtf()
+	className, rawName := g.readName()
+	g.filter(className, -1)
 	cdd.ClassDetail = append(cdd.ClassDetail, &ClassDetails{
 		ClassName: className,
+		rawName:   rawName,
 	})
 
 	//this.print("serialVersionUID - 0x" + this.byteToHex(this._data.pop()) + " " + this.byteToHex(this._data.pop()) + " " + this.byteToHex(this._data.pop()) + " " + this.byteToHex(this._data.pop()) +
 	//				   " " + this.byteToHex(this._data.pop()) + " " + this.byteToHex(this._data.pop()) + " " + this.byteToHex(this._data.pop()) + " " + this.byteToHex(this._data.pop()));
-	_ = hex.EncodeToString(g.data[0:8])
+	g.need(8)
+	cdd.ClassDetail[0].SerialVersionUID = int64(binary.BigEndian.Uint64(g.data[0:8]))
 	g.data = g.data[8:]
 
-	cdd.ClassDetail[0].RefHandle = g.handleValue
-	g.handleValue++
+	desc := cdd.ClassDetail[0]
+	desc.RefHandle = g.newHandle(desc)
 
 	g.readClassDescInfo(cdd)
+	desc.Span = g.span(start)
 
 	return cdd
 }
 
-func (g *GavaDeserilizer) readNewEnum() string {
-	return ""
+func (g *GavaDeserilizer) readNewEnum() *JavaEnum {
+	start, first := g.offset(), g.handleValue
+	g.need(1)
+	var b1 = g.data[0]
+	g.data = g.data[1:]
+
+	//fmt.Println("TC_ENUM - 0x" + hex.EncodeToString([]byte{b1}))
+
+	if b1 != 0x7e {
+		g.fail("Error: Illegal value for TC_ENUM (should be 0x7e)")
+	}
+
+	cdd := g.readClassDesc()
+	if cdd == nil {
+		g.fail("cd is nil")
+	}
+
+	e := &JavaEnum{ClassName: cdd.ClassDetail[0].ClassName, desc: cdd}
+	e.RefHandle = g.newHandle(e)
+	e.constant = g.readNewString()
+	e.Constant = e.constant.Value
+	e.Span = g.span(start)
+	g.indexed(0x7e, e.ClassName, e.RefHandle, start, first)
+	g.value(e)
+
+	return e
 }
 
-func (g *GavaDeserilizer) readNewString() string {
+func (g *GavaDeserilizer) readNewString() *JavaString {
+	g.need(1)
 	switch g.data[0] {
 	case 0x74:
 		return g.readTCString()
 	case 0x7c:
 		return g.readTCLongString()
 	case 0x71:
-		g.readPrevObject()
-		return "[TC_REF]"
+		s, ok := g.lookupHandle(g.readPrevObject()).(*JavaString)
+		if !ok {
+			g.fail("Error: TC_REFERENCE does not refer to a string.")
+		}
+		return s
 	default:
-		log.Fatal("Error illegal newString type.")
+		g.fail("Error illegal newString type.")
 	}
-	return ""
+	return nil
 }
 
-func (g *GavaDeserilizer) readTCString() string {
+func (g *GavaDeserilizer) readTCString() *JavaString {
+	start := g.offset()
+	g.need(1)
 	var b1 = g.data[0]
 	g.data = g.data[1:]
 
 	//fmt.Println("TC_STRING - 0x" + hex.EncodeToString([]byte{b1}))
 
 	if b1 != 0x74 {
-		log.Fatal("Error: Illegal value for TC_STRING (should be 0x74)")
+		g.fail("Error: Illegal value for TC_STRING (should be 0x74)")
 	}
 
-	g.handleValue++
-
-	return g.readUtf()
+	s := &JavaString{}
+	s.RefHandle = g.newHandle(s)
+	s.setValue(g.readUtfBytes())
+	s.Span = g.span(start)
+	g.indexed(b1, "java.lang.String", s.RefHandle, start, s.RefHandle)
+	return s
 }
 
-func (g *GavaDeserilizer) readTCLongString() string {
+func (g *GavaDeserilizer) readTCLongString() *JavaString {
+	start := g.offset()
+	g.need(1)
 	var b1 = g.data[0]
 	g.data = g.data[1:]
 
 	//fmt.Println("TC_LONG_STRING - 0x" + hex.EncodeToString([]byte{b1}))
 
-	if b1 != 0x74 {
-		log.Fatal("Error: Illegal value for TC_STRING (should be 0x74)")
+	if b1 != 0x7c {
+		g.fail("Error: Illegal value for TC_LONGSTRING (should be 0x7c)")
 	}
 
-	g.handleValue++
-
-	return g.readLongUtf()
+	s := &JavaString{long: true}
+	s.RefHandle = g.newHandle(s)
+	s.setValue(g.readLongUtfBytes())
+	if n := len(s.utf()); n <= 0xffff {
+		g.nonConforming(start, fmt.Sprintf("TC_LONGSTRING of %d bytes, TC_STRING holds up to 65535", n))
+	}
+	s.Span = g.span(start)
+	g.indexed(b1, "java.lang.String", s.RefHandle, start, s.RefHandle)
+	return s
 }
 
-func (g *GavaDeserilizer) readLongUtf() string {
-	var content string
-	var hexStr string
-
-	length := int64(binary.BigEndian.Uint64(g.data[0:9]))
+// readLongUtfBytes returns the modified UTF-8 bytes of a string with an 8 byte length.
+func (g *GavaDeserilizer) readLongUtfBytes() []byte {
+	g.need(8)
+	length := binary.BigEndian.Uint64(g.data[0:8])
 	g.data = g.data[8:]
 
 	//fmt.Println(fmt.Sprintf("Length - %d", length))
-	for i := int64(0); i < length; i++ {
-		var b1 = g.data[0]
-		g.data = g.data[1:]
-		content += string(b1)
-		hexStr += hex.EncodeToString([]byte{b1})
+	g.checkStringLength(length)
+	if length > uint64(len(g.data)) {
+		g.failWith(io.ErrUnexpectedEOF)
 	}
+	content := g.data[0:length]
+	g.data = g.data[length:]
 
-	//fmt.Println(fmt.Sprintf("Value - %s - 0x%s", content, hexStr))
+	//fmt.Println(fmt.Sprintf("Value - 0x%s", hex.EncodeToString(content)))
 
 	return content
 }
 
-func (g *GavaDeserilizer) readNewArray() string {
+func (g *GavaDeserilizer) readNewArray() *JavaArray {
+	g.enter()
+	defer g.leave()
+	start, first := g.offset(), g.handleValue
+	g.need(1)
 	var b1 = g.data[0]
 	g.data = g.data[1:]
 
 	//fmt.Println("TC_ARRAY - 0x" + hex.EncodeToString([]byte{b1}))
 
 	if b1 != 0x75 {
-		log.Fatal("b1 != 0x75")
+		g.fail("b1 != 0x75")
 	}
 
 	cdd := g.readClassDesc()
 	if cdd == nil {
-		log.Fatal("cd is nil")
+		g.fail("cd is nil")
 	}
 
 	if len(cdd.ClassDetail) != 1 {
-		log.Fatal("len(cdd.ClassDetail) != 1")
+		g.fail("len(cdd.ClassDetail) != 1")
 	}
 
 	cd := cdd.ClassDetail[0]
 
-	if cd.ClassName[0] != '[' {
-		log.Fatal("cd.ClassName[0] != '['")
+	if len(cd.ClassName) < 2 || cd.ClassName[0] != '[' {
+		g.fail(fmt.Sprintf("Error: Invalid array class name %q", cd.ClassName))
 	}
 
-	g.handleValue++
+	array := &JavaArray{ClassName: cd.ClassName, desc: cdd}
+	array.RefHandle = g.newHandle(array)
+	defer func() { array.Span = g.span(start) }()
 
+	g.need(4)
 	size := int(binary.BigEndian.Uint32(g.data[0:4]))
 	g.data = g.data[4:]
+	g.checkArrayLength(size)
+	g.filter(cd.ClassName, size)
+	//Every element takes at least a byte
+	if size > len(g.data) {
+		g.failWith(io.ErrUnexpectedEOF)
+	}
 	//fmt.Println(fmt.Sprintf("Array size - %d", size))
 	//fmt.Println("Values")
 
-	arrayString := "["
-
-	for i := 0; i < size-1; i++ {
+	visit := g.startVisit(func(v Visitor) Action { return v.StartArray(cd.ClassName, size, array.RefHandle) })
+	if g.visitor == nil && size > 0 {
+		array.Elements = make([]interface{}, 0, size)
+	}
+	for i := 0; i < size; i++ {
 		//fmt.Println(fmt.Sprintf("Index %d :", i))
-		value := g.readFieldValue(cd.ClassName[1])
-		arrayString += value + ", "
+		e := g.readFieldValue(cd.ClassName[1])
+		if g.visitor == nil {
+			array.Elements = append(array.Elements, e)
+		}
 	}
+	g.endVisit(visit, Visitor.EndArray)
+	g.indexed(b1, cd.ClassName, array.RefHandle, start, first)
 
-	//fmt.Println(fmt.Sprintf("Index %d :", size-1))
-	value := g.readFieldValue(cd.ClassName[1])
-	arrayString += value + "]"
-
-	return arrayString
+	return array
 }
 
-func (g *GavaDeserilizer) readNewClass() {
+func (g *GavaDeserilizer) readNewClass() *ClassDataDesc {
+	g.need(1)
 	var b1 = g.data[0]
 	g.data = g.data[1:]
 
 	//fmt.Println("TC_CLASS - 0x" + hex.EncodeToString([]byte{b1}))
 
 	if b1 != 0x76 {
-		log.Fatal("b1 != 0x76")
+		g.fail("b1 != 0x76")
 	}
 
-	g.readClassDesc()
+	cdd := g.readClassDesc()
+	if cdd == nil {
+		g.fail("cd is nil")
+	}
 
-	g.handleValue++
+	g.newHandle(cdd)
+
+	return cdd
 }
 
 func (g *GavaDeserilizer) readNewObject() *ClassDetails {
+	g.enter()
+	defer g.leave()
+	start, first := g.offset(), g.handleValue
 	var cdd *ClassDataDesc
+	g.need(1)
 	var b1 = g.data[0]
 	g.data = g.data[1:]
 
 	//fmt.Println("TC_OBJECT - 0x", hex.EncodeToString([]byte{b1}))
 	if b1 != 0x73 {
-		log.Fatal("Error: Illegal value for TC_OBJECT (should be 0x73)")
+		g.fail("Error: Illegal value for TC_OBJECT (should be 0x73)")
 	}
 
 	cdd = g.readClassDesc()
+	if cdd == nil {
+		g.fail("cd is nil")
+	}
 
-	g.handleValue++
+	obj := cdd.newObject()
+	handle := g.newHandle(obj)
+	defer func() { obj.Span = g.span(start) }()
+	for cd := obj; cd != nil; cd = cd.SuperClass {
+		cd.RefHandle = handle
+	}
+
+	visit := g.startVisit(func(v Visitor) Action { return v.StartObject(obj) })
+	g.building = append(g.building, obj)
+	g.readClassData(obj)
+	g.building = g.building[:len(g.building)-1]
+	g.endVisit(visit, Visitor.EndObject)
+	g.indexed(b1, obj.ClassName, handle, start, first)
+	//A visitor gets the values as events, objects are kept for their handles only
+	if g.visitor == nil && !g.shallow {
+		g.decodeObject(obj)
+	}
 
-	return g.readClassData(cdd)
+	return obj
 }
 
-func (g *GavaDeserilizer) readClassData(cdd *ClassDataDesc) *ClassDetails {
+func (g *GavaDeserilizer) readClassData(obj *ClassDetails) {
 	//fmt.Println("classData")
 
-	if cdd == nil {
-		return nil
+	var classes []*ClassDetails
+	for cd := obj; cd != nil; cd = cd.SuperClass {
+		classes = append(classes, cd)
 	}
 
-	for classIndex := len(cdd.ClassDetail) - 1; classIndex >= 0; classIndex-- {
-		cd := cdd.ClassDetail[classIndex]
+	for classIndex := len(classes) - 1; classIndex >= 0; classIndex-- {
+		cd := classes[classIndex]
+		start := g.offset()
 		//fmt.Println(cd.ClassName)
 		if g.isScSerializable(cd) {
 			//fmt.Println("values")
 
 			for _, cf := range cd.FieldDescription {
-				value := g.readClassDataField(cf)
-				cf.Value = value
+				fieldStart := g.offset()
+				visit := g.startVisit(func(v Visitor) Action { return v.Field(cd, cf) })
+				data := g.readClassDataField(cf)
+				g.endVisit(visit, nil)
+				if g.visitor == nil {
+					cf.Data = data
+					if !g.noValues {
+						cf.Value = valueString(cf.Data)
+						g.checkValueLength(len(cf.Value))
+					}
+					cf.Span = g.span(fieldStart)
+				}
 			}
 		}
 
+		//Without SC_BLOCKDATA, only the class knows where its external data ends
+		if g.isSCExternalizable(cd) && !g.isSCBlockData(cd) {
+			g.fail("Error: Externalizable data of " + cd.ClassName + " written without SC_BLOCKDATA can't be read")
+		}
 		if (g.isScSerializable(cd) && g.isSCWriteMethod(cd)) || (g.isSCExternalizable(cd) && g.isSCBlockData(cd)) {
 			//Start the object annotations section and indent
 			//fmt.Println("objectAnnotation")
 			//Loop until we have a TC_ENDBLOCKDATA
-			var value = ""
-			for g.data[0] != 0x78 {
-				//Read a content element
-				_, v := g.readContentElement()
-				value += v
+			cd.Annotation, cd.AnnotationSpans = g.readAnnotations()
+			if g.visitor != nil {
+				cd.Annotation, cd.AnnotationSpans = nil, nil
+				continue
+			}
+			if g.noValues {
+				continue
 			}
-			cd.ObjectValue = value
-			//Pop and print the TC_ENDBLOCKDATA element
-			g.data = g.data[1:]
-			//fmt.Println("TC_ENDBLOCKDATA - 0x78")
+			var value strings.Builder
+			for _, v := range cd.Annotation {
+				value.WriteString(valueString(v))
+				g.checkValueLength(value.Len())
+			}
+			cd.ObjectValue = value.String()
+		}
+		if cd != obj {
+			cd.Span = g.span(start)
 		}
-		return cd
 	}
-	return nil
 }
 
-func (g *GavaDeserilizer) readClassDataField(cf *ClassField) string {
+func (g *GavaDeserilizer) readClassDataField(cf *ClassField) interface{} {
 	//fmt.Println(cf.Name)
 
 	return g.readFieldValue(cf.TypeCode)
 }
 
-func (g *GavaDeserilizer) readFieldValue(typeCode byte) string {
+func (g *GavaDeserilizer) readFieldValue(typeCode byte) interface{} {
+	var v interface{}
 	switch typeCode {
 	case 'B': //byte
-		return g.readByteField()
+		v = g.readByteField()
 	case 'C': //char
-		return g.readCharField()
+		v = g.readCharField()
 	case 'D': //double
-		return g.readDoubleField()
+		v = g.readDoubleField()
 	case 'F': //float
-		return g.readFloatField()
+		v = g.readFloatField()
 	case 'I': //int
-		return g.readIntField()
+		v = g.readIntField()
 	case 'J': //long
-		return g.readLongField()
+		v = g.readLongField()
 	case 'S': //short
-		return g.readShortField()
+		v = g.readShortField()
 	case 'Z': //boolean
-		return g.readBooleanField()
+		v = g.readBooleanField()
 	case '[': //array
 		return g.readArrayField()
 	case 'L': //object
 		return g.readObjectField()
 	default: //Unknown field type
-		log.Fatal("Error: Illegal field type code ('" + string(typeCode) + "', 0x" + hex.EncodeToString([]byte{typeCode}) + ")")
+		g.fail("Error: Illegal field type code ('" + string(typeCode) + "', 0x" + hex.EncodeToString([]byte{typeCode}) + ")")
 	}
-	return ""
+	g.value(v)
+	return v
 }
 
-func (g *GavaDeserilizer) readByteField() string {
+func (g *GavaDeserilizer) readByteField() int8 {
+	g.need(1)
 	var b1 = g.data[0]
 	g.data = g.data[1:]
 
-	if int(b1) >= 0x20 && int(b1) <= 0x7e {
-		//fmt.Println(fmt.Sprintf("(byte): %d", b1))
-	} else {
-		//fmt.Println(fmt.Sprintf("(byte): %d", b1))
-	}
-
-	return fmt.Sprintf("%d", b1)
+	//fmt.Println(fmt.Sprintf("(byte): %d", b1))
+	return int8(b1)
 }
 
-func (g *GavaDeserilizer) readCharField() string {
+func (g *GavaDeserilizer) readCharField() uint16 {
+	g.need(2)
 	numBytes := g.data[0:2]
 	g.data = g.data[2:]
-	c1 := uint8(binary.BigEndian.Uint16(numBytes))
+	c1 := binary.BigEndian.Uint16(numBytes)
 	//fmt.Println(fmt.Sprintf("(char): %d", c1))
-	return fmt.Sprintf("%d", c1)
+	return c1
 }
 
-func (g *GavaDeserilizer) readDoubleField() string {
+func (g *GavaDeserilizer) readDoubleField() float64 {
+	g.need(8)
 	numBytes := g.data[0:8]
 	g.data = g.data[8:]
-	d := float64(binary.BigEndian.Uint64(numBytes))
+	d := math.Float64frombits(binary.BigEndian.Uint64(numBytes))
 	//fmt.Println(fmt.Sprintf("(double): %f", d))
-	return fmt.Sprintf("%f", d)
+	return d
 }
 
-func (g *GavaDeserilizer) readFloatField() string {
+func (g *GavaDeserilizer) readFloatField() float32 {
+	g.need(4)
 	numBytes := g.data[0:4]
 	g.data = g.data[4:]
-	d := float32(binary.BigEndian.Uint32(numBytes))
+	d := math.Float32frombits(binary.BigEndian.Uint32(numBytes))
 	//fmt.Println(fmt.Sprintf("(float): %f", d))
-	return fmt.Sprintf("%f", d)
+	return d
 }
 
-func (g *GavaDeserilizer) readIntField() string {
+func (g *GavaDeserilizer) readIntField() int32 {
+	g.need(4)
 	numBytes := g.data[0:4]
 	g.data = g.data[4:]
 	d := int32(binary.BigEndian.Uint32(numBytes))
 	//fmt.Println(fmt.Sprintf("(int): %d", d))
-	return fmt.Sprintf("%d", d)
+	return d
 }
 
-func (g *GavaDeserilizer) readLongField() string {
+func (g *GavaDeserilizer) readLongField() int64 {
+	g.need(8)
 	numBytes := g.data[0:8]
 	g.data = g.data[8:]
 	d := int64(binary.BigEndian.Uint64(numBytes))
 	//fmt.Println(fmt.Sprintf("(long): %d", d))
-	return fmt.Sprintf("%d", d)
+	return d
 }
 
-func (g *GavaDeserilizer) readShortField() string {
+func (g *GavaDeserilizer) readShortField() int16 {
+	g.need(2)
 	numBytes := g.data[0:2]
 	g.data = g.data[2:]
-	c1 := int8(binary.BigEndian.Uint16(numBytes))
-	//fmt.Println(fmt.Sprintf("(char): %d", c1))
-	return fmt.Sprintf("%d", c1)
+	c1 := int16(binary.BigEndian.Uint16(numBytes))
+	//fmt.Println(fmt.Sprintf("(short): %d", c1))
+	return c1
 }
 
-func (g *GavaDeserilizer) readBooleanField() string {
+func (g *GavaDeserilizer) readBooleanField() bool {
+	g.need(1)
 	var b1 = g.data[0]
 	g.data = g.data[1:]
 
 	//fmt.Println(fmt.Sprintf("(boolean): %d", b1))
-	return fmt.Sprintf("%d", b1)
+	return b1 != 0
 }
 
-func (g *GavaDeserilizer) readArrayField() string {
+func (g *GavaDeserilizer) readArrayField() interface{} {
 	//fmt.Println("(array)")
+	g.nesting++
+	defer func() { g.nesting-- }()
+	if v, ok := g.skipIndexed(); ok {
+		return v
+	}
+	g.need(1)
 	switch g.data[0] {
 	case 0x70:
-		return g.readNullReference()
+		g.readNullReference()
+		g.value(nil)
+		return nil
 	case 0x75:
 		return g.readNewArray()
 	case 0x71:
-		g.readPrevObject()
-		return ""
+		return g.readReference()
 	default:
-		log.Fatal("Error: Unexpected array field value type")
+		g.fail("Error: Unexpected array field value type")
 	}
-	return ""
+	return nil
 }
 
-func (g *GavaDeserilizer) readObjectField() string {
+func (g *GavaDeserilizer) readObjectField() interface{} {
 	//fmt.Println("(object)")
+	g.nesting++
+	defer func() { g.nesting-- }()
+	if v, ok := g.skipIndexed(); ok {
+		return v
+	}
+	g.need(1)
 	switch g.data[0] {
 	case 0x73:
-		f := g.readNewObject()
-		s, _ := json.Marshal(f)
-		return string(s)
+		return g.readNewObject()
 	case 0x71:
-		g.readPrevObject()
+		return g.readReference()
 	case 0x70:
-		return g.readNullReference()
+		g.readNullReference()
+		g.value(nil)
+		return nil
 	case 0x74:
-		return g.readTCString()
+		fallthrough
+	case 0x7c:
+		str := g.readNewString()
+		g.value(str)
+		return str
 	case 0x76:
-		g.readNewClass()
+		c := g.readNewClass()
+		g.value(c)
+		return c
 	case 0x75:
 		return g.readNewArray()
+	case 0x7e:
+		return g.readNewEnum()
+	default:
+		g.fail("Error: Unexpected object field value type")
 	}
-	return ""
+	return nil
 }
 
 func (g *GavaDeserilizer) isSCBlockData(cd *ClassDetails) bool {
@@ -754,6 +1163,7 @@ func (g *GavaDeserilizer) isSCWriteMethod(cd *ClassDetails) bool {
 
 func (g *GavaDeserilizer) readClassDesc() *ClassDataDesc {
 	var refHandle int
+	g.need(1)
 	switch g.data[0] {
 	case 0x72: //TC_CLASSDESC
 		fallthrough
@@ -763,19 +1173,14 @@ func (g *GavaDeserilizer) readClassDesc() *ClassDataDesc {
 		g.readNullReference()
 		return nil
 	case 0x71: //TC_REFERENCE
-		refHandle = g.readPrevObject()                //Look up a referenced class data description object and return it
-		for _, cdd := range g.classDataDescriptions { //Iterate over all class data descriptions
-			for classIndex := 0; classIndex < len(cdd.ClassDetail); classIndex++ { //Iterate over all classes in this class data description
-				if cdd.ClassDetail[classIndex].RefHandle == refHandle { //Check if the reference handle matches
-					return cdd.buildClassDataDescFromIndex(classIndex) //Generate a ClassDataDesc starting from the given index and return it
-				}
-			}
+		refHandle = g.readPrevObject() //Look up a referenced class data description object and return it
+		if cdd := g.descriptorRef(refHandle); cdd != nil {
+			return cdd
 		}
 		//Invalid classDesc reference handle
-		log.Fatal("Error: Invalid classDesc reference (0x" + string(refHandle))
+		g.problem(fmt.Sprintf("Error: Invalid classDesc reference (0x%x)", refHandle))
 	default:
-		print("Invalid classDesc type 0x" + hex.EncodeToString([]byte{g.data[0]}))
-		log.Fatal("Error illegal classDesc type.")
+		g.fail("Error illegal classDesc type 0x" + hex.EncodeToString([]byte{g.data[0]}) + ".")
 	}
 	return nil
 }
@@ -0,0 +1,423 @@
+package gava
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// boxedClass describes the java.lang wrapper a Go primitive is boxed into inside collections.
+type boxedClass struct {
+	className string
+	suid      int64
+	typeCode  byte
+	number    bool
+}
+
+var (
+	boxedBoolean   = boxedClass{"java.lang.Boolean", -3665804199014368530, 'Z', false}
+	boxedByte      = boxedClass{"java.lang.Byte", -7183698231559129828, 'B', true}
+	boxedCharacter = boxedClass{"java.lang.Character", 3786198910865385080, 'C', false}
+	boxedShort     = boxedClass{"java.lang.Short", 7515723908773894738, 'S', true}
+	boxedInteger   = boxedClass{"java.lang.Integer", 1360826667806852920, 'I', true}
+	boxedLong      = boxedClass{"java.lang.Long", 4290774380558885855, 'J', true}
+	boxedFloat     = boxedClass{"java.lang.Float", -2671257302660747028, 'F', true}
+	boxedDouble    = boxedClass{"java.lang.Double", -9172774392245257468, 'D', true}
+)
+
+const (
+	numberSUID    = -8742448824652078965
+	arrayListSUID = 8683452581122892189
+	hashMapSUID   = 362498820763181265
+)
+
+// Marshal returns the Java serialization stream of v.
+//
+// Structs are written as objects of the class named by the javaclass tag of one of their
+// fields, usually a blank one, with the serialVersionUID of its suid tag:
+//
+//	type Order struct {
+//		_     struct{} `javaclass:"com.acme.Order" suid:"123"`
+//		ID    int64    `java:"id"`
+//		Lines []Line
+//	}
+//
+// Exported fields are written under the name of their java tag or, without one, their
+// name with the first letter in lower case, ordered the way ObjectStreamClass orders them.
+// An embedded struct without a tag is written as the super class. bool, int8, uint8, int16,
+// uint16, int32, int, int64, float32 and float64 become the Java primitives of the same
+// size, int being an int, strings become String, []byte becomes byte[], other slices and
+// arrays become java.util.ArrayList and maps java.util.HashMap. Primitives inside
+// collections and interfaces are boxed. Pointers to the same struct are written once and
+// referenced afterwards, and values already in the parser's node types are written as they are.
+func Marshal(v interface{}) ([]byte, error) {
+	m := &marshalState{objects: map[interface{}]*ClassDetails{}}
+	content, err := m.content(reflect.ValueOf(v))
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf).Encode(content); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnsupportedTypeError is returned by Marshal for Go values that have no Java form.
+type UnsupportedTypeError struct {
+	Type   reflect.Type
+	Reason string
+}
+
+func (e *UnsupportedTypeError) Error() string {
+	if e.Reason != "" {
+		return "gava: unsupported type " + e.Type.String() + ": " + e.Reason
+	}
+	return "gava: unsupported type " + e.Type.String()
+}
+
+type marshalState struct {
+	// objects keeps the object written for each struct pointer so it is referenced afterwards
+	objects map[interface{}]*ClassDetails
+}
+
+var nodeTypes = map[reflect.Type]bool{
+	reflect.TypeOf((*ClassDetails)(nil)):  true,
+	reflect.TypeOf((*ClassDataDesc)(nil)): true,
+	reflect.TypeOf((*JavaString)(nil)):    true,
+	reflect.TypeOf((*JavaArray)(nil)):     true,
+	reflect.TypeOf((*JavaEnum)(nil)):      true,
+}
+
+// content returns the node written for a value in an Object position.
+func (m *marshalState) content(rv reflect.Value) (interface{}, error) {
+	if !rv.IsValid() {
+		return nil, nil
+	}
+	if nodeTypes[rv.Type()] {
+		if rv.IsNil() {
+			return nil, nil
+		}
+		return rv.Interface(), nil
+	}
+	switch rv.Kind() {
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return nil, nil
+		}
+		if rv.Elem().Kind() != reflect.Struct {
+			return m.content(rv.Elem())
+		}
+		if obj, ok := m.objects[rv.Interface()]; ok {
+			return obj, nil
+		}
+		obj := &ClassDetails{}
+		m.objects[rv.Interface()] = obj
+		return obj, m.object(rv.Elem(), obj)
+	case reflect.Interface:
+		if rv.IsNil() {
+			return nil, nil
+		}
+		return m.content(rv.Elem())
+	case reflect.Struct:
+		obj := &ClassDetails{}
+		return obj, m.object(rv, obj)
+	case reflect.String:
+		return rv.String(), nil
+	case reflect.Slice:
+		if rv.IsNil() {
+			return nil, nil
+		}
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return byteArrayOf(rv), nil
+		}
+		return m.arrayList(rv)
+	case reflect.Array:
+		return m.arrayList(rv)
+	case reflect.Map:
+		if rv.IsNil() {
+			return nil, nil
+		}
+		return m.hashMap(rv)
+	}
+	v, typeCode, err := primitive(rv)
+	if err != nil {
+		return nil, err
+	}
+	return boxed(boxedClasses[typeCode], v), nil
+}
+
+var boxedClasses = map[byte]boxedClass{
+	'Z': boxedBoolean,
+	'B': boxedByte,
+	'C': boxedCharacter,
+	'S': boxedShort,
+	'I': boxedInteger,
+	'J': boxedLong,
+	'F': boxedFloat,
+	'D': boxedDouble,
+}
+
+// primitive returns the Java primitive value and type code of a Go number or bool.
+func primitive(rv reflect.Value) (interface{}, byte, error) {
+	switch rv.Kind() {
+	case reflect.Bool:
+		return rv.Bool(), 'Z', nil
+	case reflect.Int8:
+		return int8(rv.Int()), 'B', nil
+	case reflect.Uint8:
+		return int8(rv.Uint()), 'B', nil
+	case reflect.Int16:
+		return int16(rv.Int()), 'S', nil
+	case reflect.Uint16:
+		return uint16(rv.Uint()), 'C', nil
+	case reflect.Int32:
+		return int32(rv.Int()), 'I', nil
+	case reflect.Int:
+		if rv.Int() < math.MinInt32 || rv.Int() > math.MaxInt32 {
+			return nil, 0, &UnsupportedTypeError{Type: rv.Type(), Reason: strconv.FormatInt(rv.Int(), 10) + " overflows a Java int"}
+		}
+		return int32(rv.Int()), 'I', nil
+	case reflect.Int64:
+		return rv.Int(), 'J', nil
+	case reflect.Float32:
+		return float32(rv.Float()), 'F', nil
+	case reflect.Float64:
+		return rv.Float(), 'D', nil
+	}
+	return nil, 0, &UnsupportedTypeError{Type: rv.Type()}
+}
+
+func boxed(class boxedClass, v interface{}) *ClassDetails {
+	obj := &ClassDetails{
+		ClassName:        class.className,
+		SerialVersionUID: class.suid,
+		ClassDescFlags:   0x02,
+		FieldDescription: []*ClassField{{TypeCode: class.typeCode, Name: "value", Data: v}},
+	}
+	if class.number {
+		obj.SuperClass = &ClassDetails{ClassName: "java.lang.Number", SerialVersionUID: numberSUID, ClassDescFlags: 0x02}
+	}
+	return obj
+}
+
+func byteArrayOf(rv reflect.Value) *JavaArray {
+	array := &JavaArray{ClassName: "[B", Elements: make([]interface{}, rv.Len())}
+	for i := range array.Elements {
+		array.Elements[i] = int8(rv.Index(i).Uint())
+	}
+	return array
+}
+
+// arrayList writes the elements the way ArrayList.writeObject does: the size field, the
+// size again in block data and then every element.
+func (m *marshalState) arrayList(rv reflect.Value) (*ClassDetails, error) {
+	n := rv.Len()
+	annotation := []interface{}{blockInts(int32(n))}
+	for i := 0; i < n; i++ {
+		e, err := m.content(rv.Index(i))
+		if err != nil {
+			return nil, err
+		}
+		annotation = append(annotation, e)
+	}
+	return &ClassDetails{
+		ClassName:        "java.util.ArrayList",
+		SerialVersionUID: arrayListSUID,
+		ClassDescFlags:   0x03,
+		FieldDescription: []*ClassField{{TypeCode: 'I', Name: "size", Data: int32(n)}},
+		Annotation:       annotation,
+	}, nil
+}
+
+// hashMap writes the entries the way HashMap.writeObject does: the loadFactor and threshold
+// fields, the capacity and size in block data and then every key and value. Keys are
+// sorted so the output doesn't depend on map iteration order.
+func (m *marshalState) hashMap(rv reflect.Value) (*ClassDetails, error) {
+	keys := rv.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+	})
+	capacity := 16
+	for float64(len(keys)) > float64(capacity)*0.75 {
+		capacity *= 2
+	}
+	annotation := []interface{}{blockInts(int32(capacity), int32(len(keys)))}
+	for _, k := range keys {
+		key, err := m.content(k)
+		if err != nil {
+			return nil, err
+		}
+		value, err := m.content(rv.MapIndex(k))
+		if err != nil {
+			return nil, err
+		}
+		annotation = append(annotation, key, value)
+	}
+	return &ClassDetails{
+		ClassName:        "java.util.HashMap",
+		SerialVersionUID: hashMapSUID,
+		ClassDescFlags:   0x03,
+		FieldDescription: []*ClassField{
+			{TypeCode: 'F', Name: "loadFactor", Data: float32(0.75)},
+			{TypeCode: 'I', Name: "threshold", Data: int32(capacity * 3 / 4)},
+		},
+		Annotation: annotation,
+	}, nil
+}
+
+func blockInts(values ...int32) BlockData {
+	b := make(BlockData, 4*len(values))
+	for i, v := range values {
+		binary.BigEndian.PutUint32(b[4*i:], uint32(v))
+	}
+	return b
+}
+
+// object fills obj, and its super class parts for embedded structs, from the struct rv.
+func (m *marshalState) object(rv reflect.Value, obj *ClassDetails) error {
+	t := rv.Type()
+	obj.ClassDescFlags = 0x02
+	var super reflect.Value
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if class := sf.Tag.Get("javaclass"); class != "" {
+			obj.ClassName = class
+			if suid := sf.Tag.Get("suid"); suid != "" {
+				n, err := strconv.ParseInt(suid, 10, 64)
+				if err != nil {
+					return &UnsupportedTypeError{Type: t, Reason: "invalid suid " + strconv.Quote(suid)}
+				}
+				obj.SerialVersionUID = n
+			}
+			continue
+		}
+		tag := sf.Tag.Get("java")
+		if tag == "-" || (sf.PkgPath != "" && !embeddedStruct(sf)) {
+			continue
+		}
+		fv := rv.Field(i)
+		ft := sf.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if sf.Anonymous && tag == "" && ft.Kind() == reflect.Struct && !super.IsValid() {
+			if fv.Kind() == reflect.Ptr {
+				if fv.IsNil() {
+					fv = reflect.New(ft)
+				}
+				fv = fv.Elem()
+			}
+			super = fv
+			continue
+		}
+		name := tag
+		if name == "" {
+			name = lowerFirst(sf.Name)
+		}
+		f, err := m.field(fv)
+		if err != nil {
+			return err
+		}
+		f.Name = name
+		obj.FieldDescription = append(obj.FieldDescription, f)
+	}
+	if obj.ClassName == "" {
+		return &UnsupportedTypeError{Type: t, Reason: "no javaclass tag"}
+	}
+	//ObjectStreamClass puts primitive fields first and sorts them by name
+	sort.SliceStable(obj.FieldDescription, func(i, j int) bool {
+		a, b := obj.FieldDescription[i], obj.FieldDescription[j]
+		if pa, pb := a.TypeCode != 'L' && a.TypeCode != '[', b.TypeCode != 'L' && b.TypeCode != '['; pa != pb {
+			return pa
+		}
+		return a.Name < b.Name
+	})
+	if super.IsValid() {
+		obj.SuperClass = &ClassDetails{}
+		return m.object(super, obj.SuperClass)
+	}
+	return nil
+}
+
+// field returns the field description and value of a struct field.
+func (m *marshalState) field(rv reflect.Value) (*ClassField, error) {
+	if nodeTypes[rv.Type()] {
+		v, _ := m.content(rv)
+		f := &ClassField{TypeCode: 'L', Data: v}
+		if _, ok := v.(*JavaArray); ok {
+			f.TypeCode = '['
+		}
+		f.className = fieldClassName(f)
+		return f, nil
+	}
+	t := rv.Type()
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	var className string
+	switch t.Kind() {
+	case reflect.Struct:
+		className = "Ljava/lang/Object;"
+		if class := javaClass(t); class != "" {
+			className = "L" + strings.Replace(class, ".", "/", -1) + ";"
+		}
+	case reflect.String:
+		className = "Ljava/lang/String;"
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 && t.Kind() == reflect.Slice {
+			v, _ := m.content(rv)
+			return &ClassField{TypeCode: '[', className: "[B", Data: v}, nil
+		}
+		className = "Ljava/util/List;"
+	case reflect.Map:
+		className = "Ljava/util/Map;"
+	case reflect.Interface:
+		className = "Ljava/lang/Object;"
+	default:
+		if rv.Kind() != reflect.Ptr {
+			v, typeCode, err := primitive(rv)
+			if err != nil {
+				return nil, err
+			}
+			return &ClassField{TypeCode: typeCode, Data: v}, nil
+		}
+		//Pointers to primitives are nullable and declared as the wrapper class
+		_, typeCode, err := primitive(reflect.Zero(t))
+		if err != nil {
+			return nil, err
+		}
+		className = "L" + strings.Replace(boxedClasses[typeCode].className, ".", "/", -1) + ";"
+	}
+	v, err := m.content(rv)
+	if err != nil {
+		return nil, err
+	}
+	//Structs without a javaclass tag are declared as the class they were marshaled to
+	if obj, ok := v.(*ClassDetails); ok && t.Kind() == reflect.Struct && javaClass(t) == "" {
+		className = "L" + strings.Replace(obj.ClassName, ".", "/", -1) + ";"
+	}
+	return &ClassField{TypeCode: 'L', className: className, Data: v}, nil
+}
+
+// javaClass returns the class named by the javaclass tag of the struct type t, or "".
+func javaClass(t reflect.Type) string {
+	for i := 0; i < t.NumField(); i++ {
+		if class := t.Field(i).Tag.Get("javaclass"); class != "" {
+			return class
+		}
+	}
+	return ""
+}
+
+func lowerFirst(s string) string {
+	r, n := utf8.DecodeRuneInString(s)
+	return string(unicode.ToLower(r)) + s[n:]
+}
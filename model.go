@@ -1,22 +1,125 @@
 package gava
 
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
 type ClassField struct {
-	TypeCode  byte
-	Name      string
-	className string
-	Value     string
+	TypeCode   byte
+	Name       string
+	className  string
+	typeString *JavaString
+	// rawName keeps the bytes of names whose modified UTF-8 is not the one Java writes
+	rawName []byte
+	Value   string
+	Data    interface{} `json:"-"`
+	// Span is where the value is in objects, and the field description in class descriptors.
+	Span Span `json:"-"`
 }
 
 type ClassDetails struct {
 	ClassName        string
+	SerialVersionUID int64
 	RefHandle        int
 	ClassDescFlags   byte
 	FieldDescription []*ClassField
-	ObjectValue string
+	// ProxyInterfaces are the interfaces of a dynamic proxy class, its descriptor has no
+	// class name, serialVersionUID or fields.
+	ProxyInterfaces []string
+	ObjectValue     string
+	SuperClass      *ClassDetails
+	Annotation      []interface{} `json:"-"`
+	// AnnotationSpans are the spans of the elements of Annotation.
+	AnnotationSpans []Span      `json:"-"`
+	Decoded         interface{} `json:"-"`
+	// Span is the whole object from TC_OBJECT, and the class data of their class for the
+	// SuperClass parts of objects. Class descriptors span up to the end of their super class
+	// descriptors.
+	Span Span `json:"-"`
+	desc *ClassDetails
+	// rawName and rawInterfaces keep the bytes of names whose modified UTF-8 is not the one
+	// Java writes
+	rawName       []byte
+	rawInterfaces [][]byte
 }
 
 type ClassDataDesc struct {
 	ClassDetail []*ClassDetails
+	// descriptor marks a TC_CLASSDESC or TC_PROXYCLASSDESC read as a content element of its
+	// own rather than for a TC_CLASS
+	descriptor bool
+}
+
+// JavaString is a TC_STRING or TC_LONGSTRING object.
+type JavaString struct {
+	Value     string
+	RefHandle int
+	Span      Span
+	long      bool
+	// raw keeps the bytes of strings whose modified UTF-8 is not the one Java writes
+	raw []byte
+}
+
+// JavaArray is a TC_ARRAY object, ClassName is the array descriptor such as "[B".
+type JavaArray struct {
+	ClassName string
+	Elements  []interface{}
+	RefHandle int
+	Span      Span
+	desc      *ClassDataDesc
+}
+
+// JavaEnum is a TC_ENUM constant.
+type JavaEnum struct {
+	ClassName string
+	Constant  string
+	RefHandle int
+	Span      Span
+	desc      *ClassDataDesc
+	constant  *JavaString
+}
+
+// BlockData is the content of a TC_BLOCKDATA or TC_BLOCKDATALONG element. Decoded block data
+// shares the storage of the stream it was read from.
+type BlockData []byte
+
+// Reset is a TC_RESET in the content of a stream.
+type Reset struct{}
+
+func (s *JavaString) setValue(b []byte) {
+	if isASCII(b) {
+		s.Value = string(b)
+		return
+	}
+	s.Value = decodeModifiedUTF8(b)
+	s.raw = rawUTF(b, s.Value)
+}
+
+// utf returns the bytes the string is written with.
+func (s *JavaString) utf() []byte {
+	return utfBytes(s.Value, s.raw)
+}
+
+// rawUTF returns a copy of b, the modified UTF-8 of s, when it isn't the one Java writes for
+// s, or nil when it is.
+func rawUTF(b []byte, s string) []byte {
+	if isASCII(b) || bytes.Equal(encodeModifiedUTF8(s), b) {
+		return nil
+	}
+	return append([]byte{}, b...)
+}
+
+// utfBytes returns the bytes s is written with: raw while s is still the string raw holds.
+func utfBytes(s string, raw []byte) []byte {
+	if raw != nil && decodeModifiedUTF8(raw) == s {
+		return raw
+	}
+	return encodeModifiedUTF8(s)
 }
 
 func (cd *ClassDataDesc) buildClassDataDescFromIndex(index int) *ClassDataDesc {
@@ -26,3 +129,116 @@ func (cd *ClassDataDesc) buildClassDataDescFromIndex(index int) *ClassDataDesc {
 	}
 	return &ClassDataDesc{ClassDetail: list}
 }
+
+// newObject creates an object for the class hierarchy described by cd, linking every class to its super class.
+func (cd *ClassDataDesc) newObject() *ClassDetails {
+	var obj, last *ClassDetails
+	for _, desc := range cd.ClassDetail {
+		instance := desc.newInstance()
+		if obj == nil {
+			obj = instance
+		} else {
+			last.SuperClass = instance
+		}
+		last = instance
+	}
+	return obj
+}
+
+// newInstance copies the class description so field values of different objects don't share storage.
+func (cd *ClassDetails) newInstance() *ClassDetails {
+	instance := &ClassDetails{
+		ClassName:        cd.ClassName,
+		SerialVersionUID: cd.SerialVersionUID,
+		RefHandle:        cd.RefHandle,
+		ClassDescFlags:   cd.ClassDescFlags,
+		ProxyInterfaces:  cd.ProxyInterfaces,
+		desc:             cd,
+	}
+	if len(cd.FieldDescription) == 0 {
+		return instance
+	}
+	//The fields of an instance are allocated together
+	fields := make([]ClassField, len(cd.FieldDescription))
+	instance.FieldDescription = make([]*ClassField, len(fields))
+	for i, f := range cd.FieldDescription {
+		fields[i] = ClassField{TypeCode: f.TypeCode, Name: f.Name, className: f.className}
+		instance.FieldDescription[i] = &fields[i]
+	}
+	return instance
+}
+
+// Field returns the field with the given name, looking through the super classes as well.
+func (cd *ClassDetails) Field(name string) *ClassField {
+	for c := cd; c != nil; c = c.SuperClass {
+		for _, f := range c.FieldDescription {
+			if f.Name == name {
+				return f
+			}
+		}
+	}
+	return nil
+}
+
+// valueString formats a decoded value the way it is reported in ClassField.Value. Objects
+// that aren't decoded are reported as JSON.
+func valueString(v interface{}) string {
+	return formatValue(v, nil)
+}
+
+// formatValue formats v, arrays already being formatted are reported as [...] the way
+// Arrays.deepToString does.
+func formatValue(v interface{}, arrays map[*JavaArray]bool) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case *JavaString:
+		return v.Value
+	case int8:
+		return strconv.Itoa(int(v))
+	case uint16:
+		return strconv.Itoa(int(v))
+	case int16:
+		return strconv.Itoa(int(v))
+	case int32:
+		return strconv.Itoa(int(v))
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case bool:
+		return strconv.FormatBool(v)
+	case *JavaEnum:
+		return v.Constant
+	case BlockData:
+		return hex.EncodeToString(v)
+	case *JavaArray:
+		if arrays[v] {
+			return "[...]"
+		}
+		if arrays == nil {
+			arrays = map[*JavaArray]bool{}
+		}
+		arrays[v] = true
+		defer delete(arrays, v)
+		values := make([]string, len(v.Elements))
+		for i, e := range v.Elements {
+			values[i] = formatValue(e, arrays)
+		}
+		return "[" + strings.Join(values, ", ") + "]"
+	case *ClassDataDesc:
+		if v == nil || len(v.ClassDetail) == 0 {
+			return "null"
+		}
+		return v.ClassDetail[0].ClassName
+	case *ClassDetails:
+		if v == nil {
+			return "null"
+		}
+		if v.Decoded != nil {
+			return fmt.Sprint(v.Decoded)
+		}
+		s, _ := json.Marshal(v)
+		return string(s)
+	default:
+		return fmt.Sprint(v)
+	}
+}
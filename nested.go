@@ -0,0 +1,111 @@
+package gava
+
+import (
+	"bytes"
+	"fmt"
+)
+
+var streamMagic = []byte{0xac, 0xed, 0x00, 0x05}
+
+// NestedStream is a serialization stream found inside a byte array or block data, such as
+// the content of a java.security.SignedObject or java.rmi.MarshalledObject.
+type NestedStream struct {
+	// Holder is the object holding the bytes, nil for top-level ones.
+	Holder *ClassDetails
+	// Class is the class of the part of Holder holding the bytes.
+	Class string
+	// Field is the byte array field holding the stream, followed by the index of the element
+	// for arrays of byte arrays. It is empty for block data and for arrays written by a
+	// writeObject method.
+	Field string
+	// Offset is where the stream starts in the bytes.
+	Offset int
+	// Document is the decoded stream, nil when it isn't valid.
+	Document *Document
+	// Err is the error decoding the stream.
+	Err error
+}
+
+// nestedStreams finds the streams held by the byte arrays and block data of the document
+// and decodes them with one level of nesting less.
+func (o DecoderOptions) nestedStreams(doc *Document) []*NestedStream {
+	var nested []*NestedStream
+	seen := map[*JavaArray]bool{}
+	find := func(holder *ClassDetails, class, field string, b []byte) {
+		i := bytes.Index(b, streamMagic)
+		if i < 0 {
+			return
+		}
+		inner := o
+		inner.NestedDepth--
+		s := &NestedStream{Holder: holder, Class: class, Field: field, Offset: i}
+		if s.Document, s.Err = inner.ParseDocument(b[i:]); s.Err != nil {
+			s.Document = nil
+		}
+		nested = append(nested, s)
+	}
+	array := func(holder *ClassDetails, class, field string, v interface{}) bool {
+		a, ok := v.(*JavaArray)
+		if !ok || a == nil || a.ClassName != "[B" || seen[a] {
+			return false
+		}
+		seen[a] = true
+		b := make([]byte, len(a.Elements))
+		for i, e := range a.Elements {
+			n, _ := e.(int8)
+			b[i] = byte(n)
+		}
+		find(holder, class, field, b)
+		return true
+	}
+	//Consecutive blocks are searched together, writeObject splits long arrays into blocks
+	annotations := func(holder *ClassDetails, class string, values []interface{}) {
+		var block []byte
+		for _, v := range values {
+			if b, ok := v.(BlockData); ok {
+				block = append(block, b...)
+				continue
+			}
+			if block != nil {
+				find(holder, class, "", block)
+				block = nil
+			}
+			array(holder, class, "", v)
+		}
+		if block != nil {
+			find(holder, class, "", block)
+		}
+	}
+
+	w := &walker{
+		object: func(obj *ClassDetails) {
+			for part := obj; part != nil; part = part.SuperClass {
+				for _, f := range part.FieldDescription {
+					if array(obj, part.ClassName, f.Name, f.Data) {
+						continue
+					}
+					if a, ok := f.Data.(*JavaArray); ok && a != nil {
+						for i, e := range a.Elements {
+							array(obj, part.ClassName, fmt.Sprintf("%s[%d]", f.Name, i), e)
+						}
+					}
+				}
+				annotations(obj, part.ClassName, part.Annotation)
+			}
+		},
+	}
+	//Top-level block data and arrays are searched between the objects, in stream order
+	var top []interface{}
+	for _, c := range doc.Contents {
+		switch c.(type) {
+		case BlockData, *JavaArray:
+			top = append(top, c)
+		default:
+			annotations(nil, "", top)
+			top = nil
+		}
+		w.walk([]interface{}{c})
+	}
+	annotations(nil, "", top)
+	return nested
+}
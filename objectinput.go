@@ -0,0 +1,167 @@
+package gava
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"unicode/utf16"
+)
+
+// ErrEndOfData is returned when an ObjectInput has no more data to read.
+var ErrEndOfData = errors.New("gava: end of object data")
+
+// ObjectInput reads the data a class wrote from its writeObject or writeExternal
+// method, in the order it was written: primitives come from the block data and
+// objects from the content elements between the blocks.
+type ObjectInput struct {
+	contents []interface{}
+	block    []byte
+}
+
+// NewObjectInput returns an ObjectInput over the annotation of cd, which must be
+// the part of an object that belongs to the class that wrote the data.
+func NewObjectInput(cd *ClassDetails) *ObjectInput {
+	return &ObjectInput{contents: cd.Annotation}
+}
+
+func (in *ObjectInput) read(n int) ([]byte, error) {
+	for len(in.block) < n {
+		if len(in.contents) == 0 {
+			return nil, ErrEndOfData
+		}
+		b, ok := in.contents[0].(BlockData)
+		if !ok {
+			return nil, fmt.Errorf("gava: expected block data, found %T", in.contents[0])
+		}
+		in.block = append(in.block, b...)
+		in.contents = in.contents[1:]
+	}
+	b := in.block[:n]
+	in.block = in.block[n:]
+	return b, nil
+}
+
+// ReadBoolean reads a boolean written by DataOutput.writeBoolean.
+func (in *ObjectInput) ReadBoolean() (bool, error) {
+	b, err := in.read(1)
+	if err != nil {
+		return false, err
+	}
+	return b[0] != 0, nil
+}
+
+// ReadByte reads a byte written by DataOutput.writeByte.
+func (in *ObjectInput) ReadByte() (byte, error) {
+	b, err := in.read(1)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+// ReadChar reads a UTF-16 code unit written by DataOutput.writeChar.
+func (in *ObjectInput) ReadChar() (uint16, error) {
+	b, err := in.read(2)
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint16(b), nil
+}
+
+// ReadShort reads a short written by DataOutput.writeShort.
+func (in *ObjectInput) ReadShort() (int16, error) {
+	c, err := in.ReadChar()
+	return int16(c), err
+}
+
+// ReadInt reads an int written by DataOutput.writeInt.
+func (in *ObjectInput) ReadInt() (int32, error) {
+	b, err := in.read(4)
+	if err != nil {
+		return 0, err
+	}
+	return int32(binary.BigEndian.Uint32(b)), nil
+}
+
+// ReadLong reads a long written by DataOutput.writeLong.
+func (in *ObjectInput) ReadLong() (int64, error) {
+	b, err := in.read(8)
+	if err != nil {
+		return 0, err
+	}
+	return int64(binary.BigEndian.Uint64(b)), nil
+}
+
+// ReadFloat reads a float written by DataOutput.writeFloat.
+func (in *ObjectInput) ReadFloat() (float32, error) {
+	i, err := in.ReadInt()
+	return math.Float32frombits(uint32(i)), err
+}
+
+// ReadDouble reads a double written by DataOutput.writeDouble.
+func (in *ObjectInput) ReadDouble() (float64, error) {
+	l, err := in.ReadLong()
+	return math.Float64frombits(uint64(l)), err
+}
+
+// ReadUTF reads a string written by DataOutput.writeUTF.
+func (in *ObjectInput) ReadUTF() (string, error) {
+	n, err := in.ReadChar()
+	if err != nil {
+		return "", err
+	}
+	b, err := in.read(int(n))
+	if err != nil {
+		return "", err
+	}
+	return decodeModifiedUTF8(b), nil
+}
+
+// ReadObject returns the next object, all block data before it must have been read.
+func (in *ObjectInput) ReadObject() (interface{}, error) {
+	if len(in.block) > 0 {
+		return nil, fmt.Errorf("gava: %d bytes of block data left before object", len(in.block))
+	}
+	if len(in.contents) == 0 {
+		return nil, ErrEndOfData
+	}
+	v := in.contents[0]
+	if _, ok := v.(BlockData); ok {
+		return nil, errors.New("gava: expected object, found block data")
+	}
+	in.contents = in.contents[1:]
+	return v, nil
+}
+
+// decodeModifiedUTF8 decodes the modified UTF-8 used by DataOutput.writeUTF.
+func decodeModifiedUTF8(b []byte) string {
+	if isASCII(b) {
+		return string(b)
+	}
+	chars := make([]uint16, 0, len(b))
+	for i := 0; i < len(b); i++ {
+		c := uint16(b[i])
+		switch {
+		case c < 0x80:
+		case c&0xe0 == 0xc0 && i+1 < len(b):
+			c = (c&0x1f)<<6 | uint16(b[i+1]&0x3f)
+			i++
+		case c&0xf0 == 0xe0 && i+2 < len(b):
+			c = (c&0x0f)<<12 | uint16(b[i+1]&0x3f)<<6 | uint16(b[i+2]&0x3f)
+			i += 2
+		}
+		chars = append(chars, c)
+	}
+	return string(utf16.Decode(chars))
+}
+
+// isASCII reports whether b is ASCII without NUL, which modified UTF-8 writes as is.
+func isASCII(b []byte) bool {
+	for _, c := range b {
+		if c == 0 || c >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,170 @@
+package gava
+
+import (
+	"fmt"
+	"sync"
+)
+
+// ClassReader converts the class data of an object into a Go value. It is called once
+// all fields and annotations of obj have been read and its result is stored in
+// ClassDetails.Decoded. A reader may return a nil value to leave the object undecoded.
+type ClassReader func(obj *ClassDetails) (interface{}, error)
+
+var (
+	classReadersMu sync.RWMutex
+	classReaders   = map[string]ClassReader{}
+)
+
+// RegisterClassReader makes r the reader for objects of the named Java class,
+// replacing any reader registered before, including the built-in ones.
+func RegisterClassReader(className string, r ClassReader) {
+	classReadersMu.Lock()
+	defer classReadersMu.Unlock()
+	classReaders[className] = r
+}
+
+func lookupClassReader(className string) ClassReader {
+	classReadersMu.RLock()
+	defer classReadersMu.RUnlock()
+	return classReaders[className]
+}
+
+// decodeObject runs the reader registered for the class of obj, or else for the closest
+// super class that has one. Objects a reader fails on are left undecoded, the failure is
+// recorded as a warning in lenient mode.
+func (g *GavaDeserilizer) decodeObject(obj *ClassDetails) {
+	if err := decode(obj); err != nil && g.lenient {
+		err = fmt.Errorf("cannot decode %s: %w", obj.ClassName, err)
+		g.diagnostics = append(g.diagnostics, Diagnostic{Offset: g.offset(), Severity: DiagnosticWarning, Err: err})
+	}
+}
+
+func decode(obj *ClassDetails) error {
+	for cd := obj; cd != nil; cd = cd.SuperClass {
+		r := lookupClassReader(cd.ClassName)
+		if r == nil {
+			continue
+		}
+		v, err := r(obj)
+		if err != nil {
+			return err
+		}
+		obj.Decoded = v
+		return nil
+	}
+	return nil
+}
+
+// decodedValue converts a value read from the stream into the Go value readers hand out:
+// strings become Go strings, decoded objects their Decoded value and arrays slices.
+func decodedValue(v interface{}) interface{} {
+	return decodeValue(v, nil)
+}
+
+// decodeValue converts v, arrays that contain themselves keep the *JavaArray where they
+// refer back.
+func decodeValue(v interface{}, arrays map[*JavaArray]bool) interface{} {
+	switch v := v.(type) {
+	case *JavaString:
+		return v.Value
+	case *ClassDetails:
+		if v == nil {
+			return nil
+		}
+		if v.Decoded != nil {
+			return v.Decoded
+		}
+	case *JavaArray:
+		if v.ClassName == "[B" {
+			b, _ := byteArray(v)
+			return b
+		}
+		if arrays[v] {
+			return v
+		}
+		if arrays == nil {
+			arrays = map[*JavaArray]bool{}
+		}
+		arrays[v] = true
+		defer delete(arrays, v)
+		values := make([]interface{}, len(v.Elements))
+		for i, e := range v.Elements {
+			values[i] = decodeValue(e, arrays)
+		}
+		return values
+	}
+	return v
+}
+
+// classPart returns the part of obj that holds the data of the named class.
+func classPart(obj *ClassDetails, className string) *ClassDetails {
+	for cd := obj; cd != nil; cd = cd.SuperClass {
+		if cd.ClassName == className {
+			return cd
+		}
+	}
+	return nil
+}
+
+func fieldData(obj *ClassDetails, name string) (interface{}, error) {
+	f := obj.Field(name)
+	if f == nil {
+		return nil, fmt.Errorf("missing field %s", name)
+	}
+	return f.Data, nil
+}
+
+func intField(obj *ClassDetails, name string) (int32, error) {
+	v, err := fieldData(obj, name)
+	if err != nil {
+		return 0, err
+	}
+	i, ok := v.(int32)
+	if !ok {
+		return 0, fmt.Errorf("field %s is %T, not int", name, v)
+	}
+	return i, nil
+}
+
+func longField(obj *ClassDetails, name string) (int64, error) {
+	v, err := fieldData(obj, name)
+	if err != nil {
+		return 0, err
+	}
+	l, ok := v.(int64)
+	if !ok {
+		return 0, fmt.Errorf("field %s is %T, not long", name, v)
+	}
+	return l, nil
+}
+
+// stringField returns the value of a String field, null is returned as "".
+func stringField(obj *ClassDetails, name string) (string, error) {
+	v, err := fieldData(obj, name)
+	if err != nil {
+		return "", err
+	}
+	switch s := v.(type) {
+	case nil:
+		return "", nil
+	case *JavaString:
+		return s.Value, nil
+	}
+	return "", fmt.Errorf("field %s is %T, not String", name, v)
+}
+
+// byteArray converts a byte[] value into a Go slice, null is returned as nil.
+func byteArray(v interface{}) ([]byte, error) {
+	if v == nil {
+		return nil, nil
+	}
+	a, ok := v.(*JavaArray)
+	if !ok || a.ClassName != "[B" {
+		return nil, fmt.Errorf("%T is not a byte[]", v)
+	}
+	b := make([]byte, len(a.Elements))
+	for i, e := range a.Elements {
+		b[i] = byte(e.(int8))
+	}
+	return b, nil
+}
@@ -0,0 +1,78 @@
+package gava
+
+import "sync"
+
+// RecordComponent is a component of a Java record and its decoded value.
+type RecordComponent struct {
+	Name  string
+	Value interface{}
+}
+
+// Record is a decoded Java record with its components in canonical order.
+type Record struct {
+	ClassName  string
+	Components []RecordComponent
+}
+
+var (
+	recordsMu sync.RWMutex
+	records   = map[string][]string{}
+)
+
+// RegisterRecord declares the named class as a record with the given components in
+// canonical order. The stream neither marks records nor keeps the declaration order of
+// their components, so objects of registered records are decoded into a Record.
+func RegisterRecord(className string, components ...string) {
+	recordsMu.Lock()
+	records[className] = components
+	recordsMu.Unlock()
+	RegisterClassReader(className, readRecord)
+}
+
+func recordComponentNames(className string) ([]string, bool) {
+	recordsMu.RLock()
+	defer recordsMu.RUnlock()
+	components, ok := records[className]
+	return components, ok
+}
+
+// IsRecord reports whether cd is a registered record or is described the way records
+// are: SC_SERIALIZABLE alone, a serialVersionUID of 0 unless one is declared, no super
+// class and no class annotations. Plain classes that declare a serialVersionUID of 0
+// look the same in the stream.
+func (cd *ClassDetails) IsRecord() bool {
+	if _, ok := recordComponentNames(cd.ClassName); ok {
+		return true
+	}
+	desc := cd
+	if cd.desc != nil {
+		desc = cd.desc
+	}
+	return cd.ClassDescFlags == 0x02 && cd.SerialVersionUID == 0 && cd.SuperClass == nil && len(desc.Annotation) == 0
+}
+
+// RecordComponents returns the components of a record object, in canonical order for
+// registered records and in stream order, primitives first, otherwise.
+func (cd *ClassDetails) RecordComponents() []RecordComponent {
+	names, ok := recordComponentNames(cd.ClassName)
+	if !ok {
+		for _, f := range cd.FieldDescription {
+			names = append(names, f.Name)
+		}
+	}
+	components := make([]RecordComponent, len(names))
+	for i, name := range names {
+		components[i].Name = name
+		//Components missing from the stream keep their default value
+		for _, f := range cd.FieldDescription {
+			if f.Name == name {
+				components[i].Value = decodedValue(f.Data)
+			}
+		}
+	}
+	return components
+}
+
+func readRecord(obj *ClassDetails) (interface{}, error) {
+	return &Record{ClassName: obj.ClassName, Components: obj.RecordComponents()}, nil
+}
@@ -0,0 +1,170 @@
+package gava
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"math"
+	"strings"
+)
+
+// Redactor replaces the values of a stream with pseudonyms derived from them, so equal values
+// stay equal after redaction while the originals are hidden.
+type Redactor struct {
+	// Key keys the pseudonyms. Without a secret key, short values can be found again by
+	// trying candidates.
+	Key []byte
+	// Fields lists the primitive fields redacted as well as the strings, as a class pattern
+	// of ClassFilter and a field name, or "*" for every field: "com.acme.Customer.phone",
+	// "com.acme.**.balance" or "com.acme.Card.*".
+	Fields []string
+}
+
+// Redact replaces every string of the document with a pseudonym of 16 hex digits, leaving
+// empty strings, class names, field types and enum constant names as they are, and every
+// primitive field matching Fields with a pseudonym of its type. Descriptors, block data and
+// the references between objects are unchanged, and objects are decoded again, with
+// ClassField.Value following the change within DecoderOptions.MaxValueLength. It returns
+// the number of values replaced.
+func (d *Document) Redact(r *Redactor) int {
+	//Type strings and enum constants are part of the structure
+	keep := map[*JavaString]bool{}
+	w := &walker{
+		desc: func(desc *ClassDetails) {
+			for _, f := range desc.FieldDescription {
+				keep[f.typeString] = true
+			}
+		},
+		enum: func(enum *JavaEnum) {
+			keep[enum.constant] = true
+		},
+	}
+	w.walk(d.Contents)
+
+	count := 0
+	var objects []*ClassDetails
+	w = &walker{
+		object: func(obj *ClassDetails) {
+			objects = append(objects, obj)
+			for part := obj; part != nil; part = part.SuperClass {
+				for _, f := range part.FieldDescription {
+					if f.TypeCode != 'L' && f.TypeCode != '[' && r.redactsField(part.ClassName, f.Name) {
+						f.Data = r.primitive(f.Data)
+						count++
+					}
+				}
+			}
+		},
+		str: func(s *JavaString) {
+			if !keep[s] && s.Value != "" {
+				s.Value = r.pseudonym(s.Value)
+				s.raw = nil
+				count++
+			}
+		},
+	}
+	w.walk(d.Contents)
+
+	for _, obj := range objects {
+		for part := obj; part != nil; part = part.SuperClass {
+			for _, f := range part.FieldDescription {
+				d.setValue(f)
+			}
+		}
+		//Objects that no longer decode are left undecoded
+		if decode(obj) != nil {
+			obj.Decoded = nil
+		}
+	}
+	return count
+}
+
+// Redact rewrites the stream in data with its values redacted, see Document.Redact.
+func Redact(data []byte, r *Redactor) ([]byte, error) {
+	//The values aren't written, and formatting them takes time exponential in the nesting
+	g := NewGavaDeserilizer(data)
+	g.noValues = true
+	doc, err := g.decodeDocument()
+	if err != nil {
+		return nil, err
+	}
+	doc.Redact(r)
+	return doc.Bytes()
+}
+
+func (r *Redactor) redactsField(className, name string) bool {
+	for _, pattern := range r.Fields {
+		i := strings.LastIndexByte(pattern, '.')
+		if i < 0 {
+			continue
+		}
+		if field := pattern[i+1:]; (field == "*" || field == name) && matchClass(pattern[:i], className) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Redactor) sum(b []byte) []byte {
+	mac := hmac.New(sha256.New, r.Key)
+	mac.Write(b)
+	return mac.Sum(nil)
+}
+
+func (r *Redactor) pseudonym(s string) string {
+	return hex.EncodeToString(r.sum([]byte(s))[:8])
+}
+
+// primitive returns a pseudonym of the same type as v. Floating point pseudonyms are whole
+// numbers below a million so they stay finite.
+func (r *Redactor) primitive(v interface{}) interface{} {
+	var b [9]byte
+	switch v := v.(type) {
+	case int8:
+		b[0], b[1] = 'B', byte(v)
+	case uint16:
+		b[0] = 'C'
+		binary.BigEndian.PutUint16(b[1:], v)
+	case int16:
+		b[0] = 'S'
+		binary.BigEndian.PutUint16(b[1:], uint16(v))
+	case int32:
+		b[0] = 'I'
+		binary.BigEndian.PutUint32(b[1:], uint32(v))
+	case int64:
+		b[0] = 'J'
+		binary.BigEndian.PutUint64(b[1:], uint64(v))
+	case float32:
+		b[0] = 'F'
+		binary.BigEndian.PutUint32(b[1:], math.Float32bits(v))
+	case float64:
+		b[0] = 'D'
+		binary.BigEndian.PutUint64(b[1:], math.Float64bits(v))
+	case bool:
+		b[0] = 'Z'
+		if v {
+			b[1] = 1
+		}
+	default:
+		return v
+	}
+	n := binary.BigEndian.Uint64(r.sum(b[:]))
+	switch v.(type) {
+	case int8:
+		return int8(n)
+	case uint16:
+		return uint16(n)
+	case int16:
+		return int16(n)
+	case int32:
+		return int32(n)
+	case int64:
+		return int64(n)
+	case float32:
+		return float32(n % 1000000)
+	case float64:
+		return float64(n % 1000000)
+	}
+	return n&1 == 1
+}
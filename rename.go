@@ -0,0 +1,135 @@
+package gava
+
+import "strings"
+
+// ClassRenamer maps the names of moved or renamed Java classes. Names use the binary
+// form with dots, such as "com.acme.Order" or "com.acme.Order$Line".
+type ClassRenamer struct {
+	// Classes maps class names, nested classes of a mapped class move with it.
+	Classes map[string]string
+	// Packages maps package names, classes of sub-packages move with their package.
+	Packages map[string]string
+}
+
+// Rename returns the new name of a class, or name itself when no rule applies. Exact
+// class rules win over package rules and longer packages over shorter ones.
+func (r *ClassRenamer) Rename(name string) string {
+	if to, ok := r.Classes[name]; ok {
+		return to
+	}
+	if i := strings.IndexByte(name, '$'); i >= 0 {
+		if to, ok := r.Classes[name[:i]]; ok {
+			return to + name[i:]
+		}
+	}
+	best := ""
+	for from := range r.Packages {
+		if strings.HasPrefix(name, from+".") && len(from) > len(best) {
+			best = from
+		}
+	}
+	if best != "" {
+		return r.Packages[best] + name[len(best):]
+	}
+	return name
+}
+
+// renameDescriptor renames the class in a descriptor name that may be an array, such as
+// "[[Lcom.acme.Order;", or, with slashes set, a field type such as "Lcom/acme/Order;".
+func (r *ClassRenamer) renameDescriptor(name string, slashes bool) string {
+	dims := strings.LastIndexByte(name, '[') + 1
+	element := name[dims:]
+	if dims == 0 && !slashes {
+		return r.Rename(name)
+	}
+	if !strings.HasPrefix(element, "L") || !strings.HasSuffix(element, ";") {
+		return name
+	}
+	class := element[1 : len(element)-1]
+	if slashes {
+		class = strings.Replace(class, "/", ".", -1)
+	}
+	renamed := r.Rename(class)
+	if renamed == class {
+		return name
+	}
+	if slashes {
+		renamed = strings.Replace(renamed, ".", "/", -1)
+	}
+	return name[:dims] + "L" + renamed + ";"
+}
+
+// RenameClasses renames the classes of every descriptor, object, array and enum of the
+// document, the interfaces of proxy classes, and the types of fields referring to them. It
+// returns the number of class descriptors renamed.
+func (d *Document) RenameClasses(r *ClassRenamer) int {
+	count := 0
+	renameFields := func(fields []*ClassField) {
+		for _, f := range fields {
+			if f.TypeCode != 'L' && f.TypeCode != '[' {
+				continue
+			}
+			renamed := r.renameDescriptor(f.className, true)
+			if renamed == f.className {
+				continue
+			}
+			//The type string is shared by every field of the type, it is renamed once
+			if f.typeString != nil && f.typeString.Value == f.className {
+				f.typeString.Value = renamed
+				f.typeString.raw = nil
+			}
+			f.className = renamed
+		}
+	}
+	renameInterfaces := func(names []string) bool {
+		renamed := false
+		for i, name := range names {
+			if to := r.Rename(name); to != name {
+				names[i] = to
+				renamed = true
+			}
+		}
+		return renamed
+	}
+	w := &walker{
+		object: func(obj *ClassDetails) {
+			for part := obj; part != nil; part = part.SuperClass {
+				part.ClassName = r.renameDescriptor(part.ClassName, false)
+				renameFields(part.FieldDescription)
+				//Parts share the proxy interfaces of their descriptor
+				if part.desc == nil {
+					renameInterfaces(part.ProxyInterfaces)
+				}
+			}
+		},
+		desc: func(desc *ClassDetails) {
+			if renamed := r.renameDescriptor(desc.ClassName, false); renamed != desc.ClassName {
+				desc.ClassName = renamed
+				count++
+			}
+			if renameInterfaces(desc.ProxyInterfaces) {
+				count++
+			}
+			renameFields(desc.FieldDescription)
+		},
+		array: func(array *JavaArray) {
+			array.ClassName = r.renameDescriptor(array.ClassName, false)
+		},
+		enum: func(enum *JavaEnum) {
+			enum.ClassName = r.Rename(enum.ClassName)
+		},
+	}
+	w.walk(d.Contents)
+	return count
+}
+
+// RenameClasses rewrites the stream in data with the classes renamed, leaving everything
+// else as it was.
+func RenameClasses(data []byte, r *ClassRenamer) ([]byte, error) {
+	doc, err := ParseDocument(data)
+	if err != nil {
+		return nil, err
+	}
+	doc.RenameClasses(r)
+	return doc.Bytes()
+}
@@ -0,0 +1,222 @@
+package gava
+
+import "strings"
+
+// ClassFilter selects the classes a sanitized stream may keep. Patterns are class names, or
+// end with ".*" for the classes of a package, ".**" for those of a package and its
+// sub-packages, or "*" for every class whose name starts with the rest of the pattern.
+type ClassFilter struct {
+	// Allow lists the classes kept. Every class is allowed when it is empty.
+	Allow []string
+	// Deny lists the classes removed, even when they are allowed.
+	Deny []string
+}
+
+// Allowed reports whether the filter keeps the class. Arrays are judged by their element
+// class, and arrays of primitives are always allowed.
+func (f *ClassFilter) Allowed(name string) bool {
+	name, ok := elementClass(name)
+	if !ok {
+		return true
+	}
+	for _, pattern := range f.Deny {
+		if matchClass(pattern, name) {
+			return false
+		}
+	}
+	if len(f.Allow) == 0 {
+		return true
+	}
+	for _, pattern := range f.Allow {
+		if matchClass(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// elementClass returns the class of the elements of an array class, or name itself when
+// it isn't one. It returns false for arrays of primitives.
+func elementClass(name string) (string, bool) {
+	element := strings.TrimLeft(name, "[")
+	if len(element) == len(name) {
+		return name, true
+	}
+	if strings.HasPrefix(element, "L") && strings.HasSuffix(element, ";") {
+		return element[1 : len(element)-1], true
+	}
+	return "", false
+}
+
+func matchClass(pattern, name string) bool {
+	switch {
+	case strings.HasSuffix(pattern, ".**"):
+		return strings.HasPrefix(name, pattern[:len(pattern)-2])
+	case strings.HasSuffix(pattern, ".*"):
+		pkg := pattern[:len(pattern)-1]
+		return strings.HasPrefix(name, pkg) && !strings.Contains(name[len(pkg):], ".")
+	case strings.HasSuffix(pattern, "*"):
+		return strings.HasPrefix(name, pattern[:len(pattern)-1])
+	}
+	return pattern == name
+}
+
+// Sanitize replaces every object, array, enum and class of the document whose class, or one
+// of whose super classes, the filter doesn't allow with null, and every reference to it as
+// well. Class descriptors that are written on their own, and class annotations, are
+// filtered too, so the written stream names no class outside the filter. Objects that held
+// a removed value are decoded again. It returns the number of values removed.
+func (d *Document) Sanitize(f *ClassFilter) int {
+	s := &sanitizer{doc: d, filter: f, removed: map[interface{}]bool{}, seen: map[interface{}]bool{}}
+	for i, c := range d.Contents {
+		d.Contents[i] = s.value(c)
+	}
+	return len(s.removed)
+}
+
+// Sanitize rewrites the stream in data keeping only the classes the filter allows, see
+// Document.Sanitize.
+func Sanitize(data []byte, f *ClassFilter) ([]byte, error) {
+	//The values aren't written, and formatting them takes time exponential in the nesting
+	g := NewGavaDeserilizer(data)
+	g.noValues = true
+	doc, err := g.decodeDocument()
+	if err != nil {
+		return nil, err
+	}
+	doc.Sanitize(f)
+	return doc.Bytes()
+}
+
+type sanitizer struct {
+	doc     *Document
+	filter  *ClassFilter
+	removed map[interface{}]bool
+	seen    map[interface{}]bool
+}
+
+// value returns v, or nil when v is removed, after sanitizing what v holds.
+func (s *sanitizer) value(v interface{}) interface{} {
+	switch v := v.(type) {
+	case *ClassDetails:
+		if v == nil || s.remove(v, s.objectAllowed(v)) {
+			return nil
+		}
+		if !s.seen[v] {
+			s.seen[v] = true
+			s.object(v)
+		}
+	case *JavaArray:
+		if v == nil || s.remove(v, s.filter.Allowed(v.ClassName)) {
+			return nil
+		}
+		if !s.seen[v] {
+			s.seen[v] = true
+			if v.desc != nil {
+				s.descriptors(v.desc)
+			}
+			for i, e := range v.Elements {
+				v.Elements[i] = s.value(e)
+			}
+		}
+	case *JavaEnum:
+		if v == nil || s.remove(v, s.filter.Allowed(v.ClassName)) {
+			return nil
+		}
+		if v.desc != nil {
+			s.descriptors(v.desc)
+		}
+	case *ClassDataDesc:
+		if v == nil {
+			return v
+		}
+		allowed := true
+		for _, desc := range v.ClassDetail {
+			allowed = allowed && s.classAllowed(desc)
+		}
+		if s.remove(v, allowed) {
+			return nil
+		}
+		s.descriptors(v)
+	}
+	return v
+}
+
+// remove records v as removed unless it is allowed, and reports whether it was.
+func (s *sanitizer) remove(v interface{}, allowed bool) bool {
+	if s.removed[v] {
+		return true
+	}
+	if !allowed {
+		s.removed[v] = true
+	}
+	return !allowed
+}
+
+func (s *sanitizer) objectAllowed(obj *ClassDetails) bool {
+	for part := obj; part != nil; part = part.SuperClass {
+		if !s.classAllowed(part) {
+			return false
+		}
+	}
+	return true
+}
+
+// classAllowed judges proxy classes by their interfaces.
+func (s *sanitizer) classAllowed(cd *ClassDetails) bool {
+	if cd.ProxyInterfaces == nil {
+		return s.filter.Allowed(cd.ClassName)
+	}
+	for _, name := range cd.ProxyInterfaces {
+		if !s.filter.Allowed(name) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *sanitizer) object(obj *ClassDetails) {
+	changed := false
+	for part := obj; part != nil; part = part.SuperClass {
+		if part.desc != nil {
+			s.descriptor(part.desc)
+		}
+		for _, f := range part.FieldDescription {
+			if data := s.value(f.Data); data != f.Data {
+				f.Data = data
+				s.doc.setValue(f)
+				changed = true
+			}
+		}
+		for i, a := range part.Annotation {
+			//Block data holds no classes, and can't be compared
+			if _, ok := a.(BlockData); ok {
+				continue
+			}
+			if data := s.value(a); data != a {
+				part.Annotation[i] = data
+				changed = true
+			}
+		}
+	}
+	//Objects that no longer decode are left undecoded
+	if changed && decode(obj) != nil {
+		obj.Decoded = nil
+	}
+}
+
+func (s *sanitizer) descriptors(cdd *ClassDataDesc) {
+	for _, desc := range cdd.ClassDetail {
+		s.descriptor(desc)
+	}
+}
+
+func (s *sanitizer) descriptor(desc *ClassDetails) {
+	if s.seen[desc] {
+		return
+	}
+	s.seen[desc] = true
+	for i, a := range desc.Annotation {
+		desc.Annotation[i] = s.value(a)
+	}
+}
@@ -0,0 +1,161 @@
+package gava
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Option is a decoded scala.Option, Defined is false for None.
+type Option struct {
+	Value   interface{}
+	Defined bool
+}
+
+func init() {
+	RegisterClassReader("scala.collection.immutable.List$SerializationProxy", readScalaListProxy)
+	RegisterClassReader("scala.collection.immutable.Nil$", readEmptyList)
+	RegisterClassReader("scala.collection.generic.DefaultSerializationProxy", readScalaDefaultProxy)
+	RegisterClassReader("scala.collection.immutable.HashMap$SerializationProxy", readScalaHashMapProxy)
+	RegisterClassReader("scala.collection.immutable.HashSet$SerializationProxy", readScalaHashSetProxy)
+	RegisterClassReader("scala.Some", readScalaSome)
+	RegisterClassReader("scala.None$", readScalaNone)
+	for i := 1; i <= 22; i++ {
+		RegisterClassReader(fmt.Sprintf("scala.Tuple%d", i), readScalaTuple)
+	}
+}
+
+// readScalaListProxy reads the elements List$SerializationProxy writes up to the
+// ListSerializeEnd marker.
+func readScalaListProxy(obj *ClassDetails) (interface{}, error) {
+	in := NewObjectInput(classPart(obj, "scala.collection.immutable.List$SerializationProxy"))
+	return readElementsUntil(in, "scala.collection.immutable.ListSerializeEnd$")
+}
+
+// readScalaDefaultProxy reads the Scala 2.13 DefaultSerializationProxy, which writes the
+// number of elements, or -1 followed by a SerializeEnd marker when the size is unknown.
+// Collections built by a map factory are returned as maps.
+func readScalaDefaultProxy(obj *ClassDetails) (interface{}, error) {
+	factory, err := fieldData(obj, "factory")
+	if err != nil {
+		return nil, err
+	}
+	in := NewObjectInput(classPart(obj, "scala.collection.generic.DefaultSerializationProxy"))
+	size, err := in.ReadInt()
+	if err != nil {
+		return nil, err
+	}
+	var elements []interface{}
+	if size < 0 {
+		elements, err = readElementsUntil(in, "scala.collection.generic.SerializeEnd$")
+	} else {
+		elements, err = readElements(in, int(size))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !isScalaMapFactory(factory) {
+		return elements, nil
+	}
+	var keys, values []interface{}
+	for _, e := range elements {
+		t, ok := e.([]interface{})
+		if !ok || len(t) != 2 {
+			return nil, fmt.Errorf("map entry is %T, not a Tuple2", e)
+		}
+		keys = append(keys, t[0])
+		values = append(values, t[1])
+	}
+	return newMap(keys, values), nil
+}
+
+// isScalaMapFactory reports whether the factory, or the factory it wraps, builds maps.
+func isScalaMapFactory(factory interface{}) bool {
+	obj, ok := factory.(*ClassDetails)
+	if !ok {
+		return false
+	}
+	if strings.Contains(obj.ClassName, "Map") {
+		return true
+	}
+	if f := obj.Field("factory"); f != nil {
+		return isScalaMapFactory(f.Data)
+	}
+	return false
+}
+
+// readScalaHashMapProxy reads the Scala 2.12 HashMap proxy: the size followed by the keys
+// and values.
+func readScalaHashMapProxy(obj *ClassDetails) (interface{}, error) {
+	in := NewObjectInput(classPart(obj, "scala.collection.immutable.HashMap$SerializationProxy"))
+	size, err := in.ReadInt()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := readElements(in, 2*int(size))
+	if err != nil {
+		return nil, err
+	}
+	return mapFromEntries(entries), nil
+}
+
+func readScalaHashSetProxy(obj *ClassDetails) (interface{}, error) {
+	in := NewObjectInput(classPart(obj, "scala.collection.immutable.HashSet$SerializationProxy"))
+	size, err := in.ReadInt()
+	if err != nil {
+		return nil, err
+	}
+	return readElements(in, int(size))
+}
+
+// readScalaSome reads the value of a Some, which is named x before Scala 2.13.
+func readScalaSome(obj *ClassDetails) (interface{}, error) {
+	f := obj.Field("value")
+	if f == nil {
+		f = obj.Field("x")
+	}
+	if f == nil {
+		return nil, fmt.Errorf("missing field value")
+	}
+	return Option{Value: decodedValue(f.Data), Defined: true}, nil
+}
+
+func readScalaNone(obj *ClassDetails) (interface{}, error) {
+	return Option{}, nil
+}
+
+// readScalaTuple returns the elements of a TupleN as a slice, specialized tuples keep
+// their primitive elements in _1$mcI$sp style fields.
+func readScalaTuple(obj *ClassDetails) (interface{}, error) {
+	var tuple []interface{}
+	for i := 1; ; i++ {
+		name := fmt.Sprintf("_%d", i)
+		f := obj.Field(name)
+		if f == nil {
+			break
+		}
+		for c := obj; c != nil; c = c.SuperClass {
+			for _, sf := range c.FieldDescription {
+				if strings.HasPrefix(sf.Name, name+"$mc") {
+					f = sf
+				}
+			}
+		}
+		tuple = append(tuple, decodedValue(f.Data))
+	}
+	return tuple, nil
+}
+
+// readElementsUntil reads objects up to the object of the marker class.
+func readElementsUntil(in *ObjectInput, marker string) ([]interface{}, error) {
+	elements := []interface{}{}
+	for {
+		e, err := in.ReadObject()
+		if err != nil {
+			return nil, err
+		}
+		if obj, ok := e.(*ClassDetails); ok && obj.ClassName == marker {
+			return elements, nil
+		}
+		elements = append(elements, decodedValue(e))
+	}
+}
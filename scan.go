@@ -0,0 +1,327 @@
+package gava
+
+import (
+	"bytes"
+	_ "embed" // for the default gadget rules
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Severity ranks gadget findings: "low", "medium", "high" or "critical".
+type Severity string
+
+const (
+	SeverityLow      Severity = "low"
+	SeverityMedium   Severity = "medium"
+	SeverityHigh     Severity = "high"
+	SeverityCritical Severity = "critical"
+)
+
+// GadgetRule describes a known deserialization gadget chain.
+type GadgetRule struct {
+	ID          string   `json:"id"`
+	Description string   `json:"description"`
+	Severity    Severity `json:"severity"`
+	// Chain lists the links of the chain from the outermost one. Each link lists class
+	// patterns in the syntax of ClassFilter, and must be reached from the previous link.
+	Chain [][]string `json:"chain"`
+}
+
+// Finding is a gadget chain found in a stream.
+type Finding struct {
+	Rule        string
+	Description string
+	Severity    Severity
+	// Path lists the classes from a top-level value down to the last link of the chain.
+	// Proxy classes are shown as "$Proxy(interfaces)".
+	Path []string
+	// Offsets are the offsets in the stream where the values of Path start.
+	Offsets []int
+}
+
+//go:embed gadgets.json
+var defaultGadgetRules []byte
+
+// DefaultGadgetRules returns the rules of the gadget chains known to this package: Commons
+// Collections transformers, TemplatesImpl, PriorityQueue comparators,
+// BadAttributeValueExpException, AnnotationInvocationHandler, Spring, Groovy, ROME, C3P0 and
+// URLDNS.
+func DefaultGadgetRules() []GadgetRule {
+	rules, err := LoadGadgetRules(bytes.NewReader(defaultGadgetRules))
+	if err != nil {
+		panic(err)
+	}
+	return rules
+}
+
+// LoadGadgetRules reads rules from a JSON array in the format of DefaultGadgetRules, to
+// add to or replace them.
+func LoadGadgetRules(r io.Reader) ([]GadgetRule, error) {
+	var rules []GadgetRule
+	if err := json.NewDecoder(r).Decode(&rules); err != nil {
+		return nil, fmt.Errorf("gava: invalid gadget rules: %v", err)
+	}
+	for _, rule := range rules {
+		switch rule.Severity {
+		case SeverityLow, SeverityMedium, SeverityHigh, SeverityCritical:
+		default:
+			return nil, fmt.Errorf("gava: gadget rule %q: invalid severity %q", rule.ID, rule.Severity)
+		}
+		if rule.ID == "" || len(rule.Chain) == 0 {
+			return nil, fmt.Errorf("gava: gadget rule %q: missing id or chain", rule.ID)
+		}
+		for _, link := range rule.Chain {
+			if len(link) == 0 {
+				return nil, fmt.Errorf("gava: gadget rule %q: empty link", rule.ID)
+			}
+		}
+	}
+	return rules, nil
+}
+
+// Scanner looks for gadget chains in streams without running anything.
+type Scanner struct {
+	Rules []GadgetRule
+	// Options limits the resources spent on each stream.
+	Options DecoderOptions
+}
+
+// Scan returns the gadget chains of DefaultGadgetRules found in the stream in data.
+func Scan(data []byte) []Finding {
+	return (&Scanner{Rules: DefaultGadgetRules()}).Scan(data)
+}
+
+// Scan returns the first match of each rule in the stream in data. A rule matches a path
+// from a top-level value through objects, arrays and class annotations that reaches a class
+// of each link of the chain in order. Objects match through their super classes, arrays
+// through their element class and proxies through their interfaces.
+//
+// Streams that can't be parsed, truncated or past the limits of the options, are scanned for
+// the class descriptors they contain, which match the rules whose links appear in order.
+func (s *Scanner) Scan(data []byte) []Finding {
+	doc, err := s.Options.ParseDocument(data)
+	if err != nil {
+		return s.scanRaw(data)
+	}
+	sc := &scan{Scanner: s, seen: map[interface{}]bool{}, found: map[string]bool{}}
+	for _, c := range doc.Contents {
+		sc.value(c)
+	}
+	return sc.findings
+}
+
+type scanNode struct {
+	label   string
+	classes []string
+	offset  int
+}
+
+type scan struct {
+	*Scanner
+	seen     map[interface{}]bool
+	found    map[string]bool
+	path     []scanNode
+	findings []Finding
+}
+
+func (sc *scan) value(v interface{}) {
+	switch v := v.(type) {
+	case *ClassDetails:
+		if v == nil || sc.seen[v] {
+			return
+		}
+		sc.seen[v] = true
+		var classes []string
+		for part := v; part != nil; part = part.SuperClass {
+			classes = append(classes, partClasses(part)...)
+		}
+		sc.push(scanNode{label: partLabel(v), classes: classes, offset: v.Span.Offset})
+		for part := v; part != nil; part = part.SuperClass {
+			if part.desc != nil {
+				sc.annotations(part.desc)
+			}
+			for _, f := range part.FieldDescription {
+				sc.value(f.Data)
+			}
+			for _, a := range part.Annotation {
+				sc.value(a)
+			}
+		}
+		sc.pop()
+	case *JavaArray:
+		if v == nil || sc.seen[v] {
+			return
+		}
+		sc.seen[v] = true
+		class, _ := elementClass(v.ClassName)
+		sc.push(scanNode{label: v.ClassName, classes: []string{class}, offset: v.Span.Offset})
+		for _, e := range v.Elements {
+			sc.value(e)
+		}
+		sc.pop()
+	case *JavaEnum:
+		if v == nil || sc.seen[v] {
+			return
+		}
+		sc.seen[v] = true
+		sc.push(scanNode{label: v.ClassName, classes: []string{v.ClassName}, offset: v.Span.Offset})
+		sc.pop()
+	case *ClassDataDesc:
+		if v == nil || sc.seen[v] || len(v.ClassDetail) == 0 {
+			return
+		}
+		sc.seen[v] = true
+		var classes []string
+		for _, desc := range v.ClassDetail {
+			classes = append(classes, partClasses(desc)...)
+		}
+		sc.push(scanNode{label: partLabel(v.ClassDetail[0]), classes: classes, offset: v.ClassDetail[0].Span.Offset})
+		for _, desc := range v.ClassDetail {
+			sc.annotations(desc)
+		}
+		sc.pop()
+	}
+}
+
+// annotations scans the values a class wrote with its descriptor.
+func (sc *scan) annotations(desc *ClassDetails) {
+	if sc.seen[desc] {
+		return
+	}
+	sc.seen[desc] = true
+	for _, a := range desc.Annotation {
+		sc.value(a)
+	}
+}
+
+func (sc *scan) push(n scanNode) {
+	sc.path = append(sc.path, n)
+	for _, rule := range sc.Rules {
+		if !sc.found[rule.ID] && sc.matches(rule) {
+			sc.found[rule.ID] = true
+			f := Finding{Rule: rule.ID, Description: rule.Description, Severity: rule.Severity}
+			for _, n := range sc.path {
+				f.Path = append(f.Path, n.label)
+				f.Offsets = append(f.Offsets, n.offset)
+			}
+			sc.findings = append(sc.findings, f)
+		}
+	}
+}
+
+func (sc *scan) pop() {
+	sc.path = sc.path[:len(sc.path)-1]
+}
+
+// matches reports whether the last node of the path matches the last link of the rule, and
+// the nodes above it the other links in order.
+func (sc *scan) matches(rule GadgetRule) bool {
+	i := len(sc.path) - 1
+	for link := len(rule.Chain) - 1; link >= 0; link-- {
+		for i >= 0 && !matchLink(rule.Chain[link], sc.path[i].classes) {
+			if link == len(rule.Chain)-1 {
+				return false
+			}
+			i--
+		}
+		if i < 0 {
+			return false
+		}
+		i--
+	}
+	return true
+}
+
+func matchLink(patterns, classes []string) bool {
+	for _, class := range classes {
+		for _, pattern := range patterns {
+			if matchClass(pattern, class) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func partClasses(cd *ClassDetails) []string {
+	if cd.ProxyInterfaces != nil {
+		return cd.ProxyInterfaces
+	}
+	return []string{cd.ClassName}
+}
+
+func partLabel(cd *ClassDetails) string {
+	if cd.ProxyInterfaces != nil {
+		return "$Proxy(" + strings.Join(cd.ProxyInterfaces, ",") + ")"
+	}
+	return cd.ClassName
+}
+
+// scanRaw finds the class descriptors of a stream that can't be parsed by their bytes, and
+// matches the rules whose links appear in order.
+func (s *Scanner) scanRaw(data []byte) []Finding {
+	var nodes []scanNode
+	for i := 0; i < len(data); i++ {
+		switch data[i] {
+		case 0x72: //TC_CLASSDESC
+			if name, ok := rawClassName(data[i+1:]); ok {
+				nodes = append(nodes, scanNode{label: name, classes: []string{name}, offset: i})
+			}
+		case 0x7d: //TC_PROXYCLASSDESC
+			if i+5 > len(data) {
+				continue
+			}
+			count := int(data[i+1])<<24 | int(data[i+2])<<16 | int(data[i+3])<<8 | int(data[i+4])
+			rest := data[i+5:]
+			var interfaces []string
+			for ; count > 0 && count <= len(rest)/2; count-- {
+				name, ok := rawClassName(rest)
+				if !ok {
+					break
+				}
+				interfaces = append(interfaces, name)
+				rest = rest[2+len(name):]
+			}
+			if count == 0 && len(interfaces) > 0 {
+				desc := &ClassDetails{ProxyInterfaces: interfaces}
+				nodes = append(nodes, scanNode{label: partLabel(desc), classes: interfaces, offset: i})
+			}
+		}
+	}
+
+	var findings []Finding
+	for _, rule := range s.Rules {
+		f := Finding{Rule: rule.ID, Description: rule.Description, Severity: rule.Severity}
+		link := 0
+		for _, n := range nodes {
+			if link < len(rule.Chain) && matchLink(rule.Chain[link], n.classes) {
+				f.Path = append(f.Path, n.label)
+				f.Offsets = append(f.Offsets, n.offset)
+				link++
+			}
+		}
+		if link == len(rule.Chain) {
+			findings = append(findings, f)
+		}
+	}
+	return findings
+}
+
+// rawClassName reads a class name with a 2 byte length, if the bytes look like one.
+func rawClassName(b []byte) (string, bool) {
+	if len(b) < 2 {
+		return "", false
+	}
+	n := int(b[0])<<8 | int(b[1])
+	if n == 0 || 2+n > len(b) {
+		return "", false
+	}
+	for _, c := range b[2 : 2+n] {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.IndexByte("_$.;[", c) >= 0) {
+			return "", false
+		}
+	}
+	return string(b[2 : 2+n]), true
+}
@@ -0,0 +1,18 @@
+package gava
+
+// Span is the part of the stream a value was decoded from. Values built in Go, and values
+// changed after decoding, keep the span they have, it isn't updated by encoding.
+type Span struct {
+	Offset int
+	Length int
+}
+
+// End returns the offset after the last byte of the span.
+func (s Span) End() int {
+	return s.Offset + s.Length
+}
+
+// span returns the span from start to the current offset.
+func (g *GavaDeserilizer) span(start int) Span {
+	return Span{Offset: start, Length: g.offset() - start}
+}
@@ -0,0 +1,47 @@
+package gava
+
+// SUIDChange is a class descriptor whose serialVersionUID was rewritten.
+type SUIDChange struct {
+	ClassName string
+	Old, New  int64
+}
+
+// RewriteSUIDs sets the serialVersionUID of the class descriptors named in suids, by class
+// name, and of the objects described by them. It returns every descriptor it matched, in
+// stream order, including those that already had the new value. Array descriptors are only
+// matched by their own names, such as "[Lcom.acme.Order;".
+func (d *Document) RewriteSUIDs(suids map[string]int64) []SUIDChange {
+	var changes []SUIDChange
+	w := &walker{
+		object: func(obj *ClassDetails) {
+			for part := obj; part != nil; part = part.SuperClass {
+				if suid, ok := suids[part.ClassName]; ok {
+					part.SerialVersionUID = suid
+				}
+			}
+		},
+		desc: func(desc *ClassDetails) {
+			if suid, ok := suids[desc.ClassName]; ok {
+				changes = append(changes, SUIDChange{ClassName: desc.ClassName, Old: desc.SerialVersionUID, New: suid})
+				desc.SerialVersionUID = suid
+			}
+		},
+	}
+	w.walk(d.Contents)
+	return changes
+}
+
+// RewriteSUIDs rewrites the stream in data with the serialVersionUIDs of the classes named
+// in suids replaced, leaving everything else as it was.
+func RewriteSUIDs(data []byte, suids map[string]int64) ([]byte, []SUIDChange, error) {
+	doc, err := ParseDocument(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	changes := doc.RewriteSUIDs(suids)
+	out, err := doc.Bytes()
+	if err != nil {
+		return nil, nil, err
+	}
+	return out, changes, nil
+}
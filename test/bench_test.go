@@ -0,0 +1,89 @@
+package test
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/maPaydar/gava-deserializer"
+)
+
+func encode(b *testing.B, values ...interface{}) []byte {
+	var buf bytes.Buffer
+	e := gava.NewEncoder(&buf)
+	for _, v := range values {
+		if err := e.Encode(v); err != nil {
+			b.Fatal(err)
+		}
+	}
+	return buf.Bytes()
+}
+
+func benchmarkParse(b *testing.B, data []byte) {
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := gava.ParseDocument(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+var sizes = []int{1 << 10, 1 << 16, 1 << 20}
+
+func BenchmarkBlockData(b *testing.B) {
+	for _, n := range sizes {
+		data := encode(b, gava.BlockData(make([]byte, n)))
+		b.Run(fmt.Sprint(n), func(b *testing.B) { benchmarkParse(b, data) })
+	}
+}
+
+func BenchmarkString(b *testing.B) {
+	for _, n := range sizes {
+		data := encode(b, gava.String(strings.Repeat("a", n)))
+		b.Run(fmt.Sprint(n), func(b *testing.B) { benchmarkParse(b, data) })
+	}
+}
+
+func BenchmarkByteArray(b *testing.B) {
+	for _, n := range sizes {
+		elements := make([]interface{}, n)
+		for i := range elements {
+			elements[i] = int8(i)
+		}
+		data := encode(b, &gava.JavaArray{ClassName: "[B", Elements: elements})
+		b.Run(fmt.Sprint(n), func(b *testing.B) { benchmarkParse(b, data) })
+	}
+}
+
+// BenchmarkObjects parses an array of objects of the same class, allocations per op grow with
+// the number of objects.
+func BenchmarkObjects(b *testing.B) {
+	for _, n := range []int{100, 1000, 10000} {
+		elements := make([]interface{}, n)
+		for i := range elements {
+			elements[i] = transformer("com.acme.Item",
+				&gava.ClassField{TypeCode: 'I', Name: "id", Data: int32(i)},
+				&gava.ClassField{TypeCode: 'L', Name: "name", Data: gava.String(fmt.Sprint("item", i))})
+		}
+		data := encode(b, &gava.JavaArray{ClassName: "[Lcom.acme.Item;", Elements: elements})
+		b.Run(fmt.Sprint(n), func(b *testing.B) { benchmarkParse(b, data) })
+	}
+}
+
+// BenchmarkList parses a linked list, objects nested n deep. The ClassField.Value of each
+// node holds the JSON of the nodes after it, which grows exponentially with the depth.
+func BenchmarkList(b *testing.B) {
+	for _, n := range []int{4, 8, 12} {
+		var next interface{}
+		for i := 0; i < n; i++ {
+			next = transformer("com.acme.Node",
+				&gava.ClassField{TypeCode: 'I', Name: "id", Data: int32(i)},
+				&gava.ClassField{TypeCode: 'L', Name: "next", Data: next})
+		}
+		data := encode(b, next)
+		b.Run(fmt.Sprint(n), func(b *testing.B) { benchmarkParse(b, data) })
+	}
+}
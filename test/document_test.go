@@ -0,0 +1,61 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/maPaydar/gava-deserializer"
+	"github.com/maPaydar/gava-deserializer/pkg"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDocumentRoundTrip(t *testing.T) {
+	for _, hexB := range []string{
+		//Field type strings written again instead of referenced
+		"aced000573720023696d2e6163746f722e7365727665722e67726f75702e47726f7570536e617073686f7400000000000000010200034c00076d656d626572737400214c7363616c612f636f6c6c656374696f6e2f696d6d757461626c652f4c6973743b4c000873657474696e67737400204c7363616c612f636f6c6c656374696f6e2f696d6d757461626c652f4d61703b4c00057469746c6574000e4c7363616c612f4f7074696f6e3b7870737200327363616c612e636f6c6c656374696f6e2e696d6d757461626c652e4c6973742453657269616c697a6174696f6e50726f787900000000000000010300007870740005616c696365740003626f627372002c7363616c612e636f6c6c656374696f6e2e696d6d757461626c652e4c69737453657269616c697a65456e64248a5c635bf7530b6d020000787078737200327363616c612e636f6c6c656374696f6e2e67656e657269632e44656661756c7453657269616c697a6174696f6e50726f787900000000000000010300014c0007666163746f727974001a4c7363616c612f636f6c6c656374696f6e2f466163746f72793b7870737200257363616c612e636f6c6c656374696f6e2e4d6170466163746f727924546f466163746f727900000000000000010200014c0007666163746f727974001d4c7363616c612f636f6c6c656374696f6e2f4d6170466163746f72793b78707372001f7363616c612e636f6c6c656374696f6e2e696d6d757461626c652e4d617024000000000000000102000078707704000000017372000c7363616c612e5475706c653200000000000000010200024c00025f317400124c6a6176612f6c616e672f4f626a6563743b4c00025f327400124c6a6176612f6c616e672f4f626a6563743b78707400056d75746564737200116a6176612e6c616e672e426f6f6c65616ecd207280d59cfaee0200015a000576616c7565787001787372000a7363616c612e536f6d6500000000000000010200014c000576616c75657400124c6a6176612f6c616e672f4f626a6563743b7870740007467269656e6473",
+		//RMI ReturnData
+		"51aced0005770f0200000000000000000000000000007372001a6a6176612e6c616e672e52756e74696d65457863657074696f6e9e5f06470a3483e5020000787200136a6176612e6c616e672e457863657074696f6ed0fd1f3e1a3b1cc4020000787200136a6176612e6c616e672e5468726f7761626c65d5c635273977b8cb0300044c000563617573657400154c6a6176612f6c616e672f5468726f7761626c653b4c000d64657461696c4d6573736167657400124c6a6176612f6c616e672f537472696e673b5b000a737461636b547261636574001e5b4c6a6176612f6c616e672f537461636b5472616365456c656d656e743b4c001473757070726573736564457863657074696f6e737400104c6a6176612f7574696c2f4c6973743b7870737200136a6176612e696f2e494f457863657074696f6e6c8073646525f0ab0200007871007e000171007e00097400096469736b2066756c6c7572001e5b4c6a6176612e6c616e672e537461636b5472616365456c656d656e743b2246c53c3cfd22390200007870000000027372001b6a6176612e6c616e672e537461636b5472616365456c656d656e746109c59a2636dd8502000449000a6c696e654e756d6265724c000e6465636c6172696e67436c6173737400124c6a6176612f6c616e672f537472696e673b4c000866696c654e616d657400124c6a6176612f6c616e672f537472696e673b4c000a6d6574686f644e616d657400124c6a6176612f6c616e672f537472696e673b78700000000374000d636f6d2e61636d652e4469736b7400094469736b2e6a61766174000577726974657371007e000d0000000774000d636f6d2e61636d652e4d61696e7400094d61696e2e6a6176617400046d61696e737200326a6176612e7574696c2e436f6c6c656374696f6e7324556e6d6f6469666961626c6552616e646f6d4163636573734c697374dcb7e7951f48464f020000787200266a6176612e7574696c2e436f6c6c656374696f6e7324556e6d6f6469666961626c654c697374fc0f2531b5ec8e100200014c00046c6973747400104c6a6176612f7574696c2f4c6973743b7872002c6a6176612e7574696c2e436f6c6c656374696f6e7324556e6d6f6469666961626c65436f6c6c656374696f6e19420080cb5ef71e0200014c0001637400164c6a6176612f7574696c2f436f6c6c656374696f6e3b7870737200136a6176612e7574696c2e41727261794c6973747881d21d99c7619d03000149000473697a657870000000007704000000007871007e002078740004626f6f6d7571007e000b000000027371007e000d0000002a740010636f6d2e61636d652e5365727669636574000c536572766963652e6a61766174000463616c6c7371007e000d0000000774000d636f6d2e61636d652e4d61696e7400094d61696e2e6a6176617400046d61696e71007e001e78",
+		//TC_RESET between two strings, and a string with a NUL byte Java would write as c0 80
+		"aced000574000568656c6c6f79740003610062",
+		//A class descriptor on its own, then an object of it
+		"aced00057200045465737400000000000000000200007870" + "7371007e0000",
+		//A class named "A" and a field named "i" in overlong modified UTF-8
+		"aced000573720002c181000000000000000102000149" + "0002c1a9" + "787000000007",
+		//A proxy of an interface named "R" in overlong modified UTF-8
+		"aced0005737d000000010002c1927870",
+	} {
+		data := pkg.DecodeHex(hexB)
+
+		doc, err := gava.ParseDocument(data)
+		assert.NoError(t, err)
+		out, err := doc.Bytes()
+		assert.NoError(t, err)
+		assert.Equal(t, data, out)
+	}
+}
+
+func TestDocumentExternalizable(t *testing.T) {
+	//Test is Externalizable, written with PROTOCOL_VERSION_1: its data can't be told apart
+	//from the content that follows it
+	data := pkg.DecodeHex("aced00057372000454657374000000000000000004000078700000002a")
+
+	_, err := gava.ParseDocument(data)
+	assert.Error(t, err)
+	_, err = gava.NewGavaDeserilizer(data).Decode()
+	assert.Error(t, err)
+}
+
+func TestDocumentEdit(t *testing.T) {
+	hexB := "aced000573720023696d2e6163746f722e7365727665722e67726f75702e47726f7570536e617073686f7400000000000000010200034c00076d656d626572737400214c7363616c612f636f6c6c656374696f6e2f696d6d757461626c652f4c6973743b4c000873657474696e67737400204c7363616c612f636f6c6c656374696f6e2f696d6d757461626c652f4d61703b4c00057469746c6574000e4c7363616c612f4f7074696f6e3b7870737200327363616c612e636f6c6c656374696f6e2e696d6d757461626c652e4c6973742453657269616c697a6174696f6e50726f787900000000000000010300007870740005616c696365740003626f627372002c7363616c612e636f6c6c656374696f6e2e696d6d757461626c652e4c69737453657269616c697a65456e64248a5c635bf7530b6d020000787078737200327363616c612e636f6c6c656374696f6e2e67656e657269632e44656661756c7453657269616c697a6174696f6e50726f787900000000000000010300014c0007666163746f727974001a4c7363616c612f636f6c6c656374696f6e2f466163746f72793b7870737200257363616c612e636f6c6c656374696f6e2e4d6170466163746f727924546f466163746f727900000000000000010200014c0007666163746f727974001d4c7363616c612f636f6c6c656374696f6e2f4d6170466163746f72793b78707372001f7363616c612e636f6c6c656374696f6e2e696d6d757461626c652e4d617024000000000000000102000078707704000000017372000c7363616c612e5475706c653200000000000000010200024c00025f317400124c6a6176612f6c616e672f4f626a6563743b4c00025f327400124c6a6176612f6c616e672f4f626a6563743b78707400056d75746564737200116a6176612e6c616e672e426f6f6c65616ecd207280d59cfaee0200015a000576616c7565787001787372000a7363616c612e536f6d6500000000000000010200014c000576616c75657400124c6a6176612f6c616e672f4f626a6563743b7870740007467269656e6473"
+
+	doc, err := gava.ParseDocument(pkg.DecodeHex(hexB))
+	assert.NoError(t, err)
+	title := doc.Object().Field("title").Data.(*gava.ClassDetails)
+	title.Field("value").Data = &gava.JavaString{Value: "Family"}
+
+	out, err := doc.Bytes()
+	assert.NoError(t, err)
+	parsedObject, err := gava.NewGavaDeserilizer(out).Decode()
+	assert.NoError(t, err)
+	assert.Equal(t, gava.Option{Value: "Family", Defined: true}, parsedObject.Field("title").Data.(*gava.ClassDetails).Decoded)
+	assert.Equal(t, []interface{}{"alice", "bob"}, parsedObject.Field("members").Data.(*gava.ClassDetails).Decoded)
+}
@@ -0,0 +1,67 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/maPaydar/gava-deserializer"
+	"github.com/maPaydar/gava-deserializer/pkg"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDocumentSet(t *testing.T) {
+	hexB := "aced00057372002f696d2e6163746f722e7365727665722e6469616c6f672e47726f75704469616c6f675374617465536e617073686f74000000000000000002000449000767726f757049644c000f6c6173744d657373616765446174657400134c6a6176612f74696d652f496e7374616e743b4c000c6c617374526561644461746571007e00014c000f6c617374526563656976654461746571007e00017870000000007372000d6a6176612e74696d652e536572955d84ba1b2248b20c00007870770d02000000005b1bd9bb352ad700787371007e0003770d02000000005b056ab729f63000787371007e0003770d02000000005b1bd9bb352ad70078"
+	data := pkg.DecodeHex(hexB)
+
+	doc, err := gava.ParseDocument(data)
+	assert.NoError(t, err)
+	assert.NoError(t, doc.Set("groupId", gava.Int(42)))
+
+	out, err := doc.Bytes()
+	assert.NoError(t, err)
+	var changed []int
+	for i := range data {
+		if data[i] != out[i] {
+			changed = append(changed, i)
+		}
+	}
+	assert.Equal(t, len(data), len(out))
+	assert.Len(t, changed, 1)
+	assert.Equal(t, byte(42), out[changed[0]])
+
+	lastMessageDate, err := doc.Get("lastMessageDate")
+	assert.NoError(t, err)
+	assert.NoError(t, doc.Set("lastReadDate", lastMessageDate))
+	out, err = doc.Bytes()
+	assert.NoError(t, err)
+	parsedObject, err := gava.NewGavaDeserilizer(out).Decode()
+	assert.NoError(t, err)
+	assert.Equal(t, int32(42), parsedObject.Field("groupId").Data)
+	assert.Same(t, parsedObject.Field("lastMessageDate").Data, parsedObject.Field("lastReadDate").Data)
+
+	assert.Error(t, doc.Set("groupId", gava.Long(1)))
+	assert.Error(t, doc.Set("groupId", 1<<40))
+	assert.Error(t, doc.Set("missing", gava.Int(1)))
+	assert.Error(t, doc.Set("groupId.value", gava.Int(1)))
+}
+
+func TestSetArrayElement(t *testing.T) {
+	hexB := "aced0005737200216a6176612e6c616e672e696e766f6b652e53657269616c697a65644c616d6264616f61d0942c29368502000a49000e696d706c4d6574686f644b696e645b000c6361707475726564417267737400135b4c6a6176612f6c616e672f4f626a6563743b4c000e636170747572696e67436c6173737400114c6a6176612f6c616e672f436c6173733b4c001866756e6374696f6e616c496e74657266616365436c6173737400124c6a6176612f6c616e672f537472696e673b4c001d66756e6374696f6e616c496e746572666163654d6574686f644e616d6571007e00034c002266756e6374696f6e616c496e746572666163654d6574686f645369676e617475726571007e00034c0009696d706c436c61737371007e00034c000e696d706c4d6574686f644e616d6571007e00034c0013696d706c4d6574686f645369676e617475726571007e00034c0016696e7374616e7469617465644d6574686f645479706571007e0003787000000006757200135b4c6a6176612e6c616e672e4f626a6563743b90ce589f1073296c0200007870000000017400034555527672000f636f6d2e61636d652e4f72646572730000000000000001020000787074001c6a6176612f7574696c2f66756e6374696f6e2f50726564696361746574000474657374740015284c6a6176612f6c616e672f4f626a6563743b295a74000f636f6d2f61636d652f4f726465727374000d6c616d626461246f70656e2430740025284c6a6176612f6c616e672f537472696e673b4c636f6d2f61636d652f4f726465723b295a740013284c636f6d2f61636d652f4f726465723b295a"
+
+	parsedObject, err := gava.NewGavaDeserilizer(pkg.DecodeHex(hexB)).Decode()
+	assert.NoError(t, err)
+	assert.NoError(t, parsedObject.Set("capturedArgs[0]", "USD"))
+
+	v, err := parsedObject.Get("capturedArgs[0]")
+	assert.NoError(t, err)
+	assert.Equal(t, "USD", v.(*gava.JavaString).Value)
+	assert.Equal(t, []interface{}{"USD"}, parsedObject.Decoded.(*gava.Lambda).CapturedArgs)
+	assert.Error(t, parsedObject.Set("capturedArgs[1]", "USD"))
+
+	//Java would throw a ClassCastException or an ArrayStoreException
+	assert.Error(t, parsedObject.Set("functionalInterfaceClass", parsedObject))
+	assert.Error(t, parsedObject.Set("capturingClass", "com.acme.Orders"))
+	assert.Error(t, parsedObject.Set("capturedArgs", &gava.JavaArray{ClassName: "[I", Elements: []interface{}{int32(1)}}))
+	assert.NoError(t, parsedObject.Set("capturedArgs", &gava.JavaArray{ClassName: "[Ljava.lang.String;", Elements: []interface{}{nil}}))
+	assert.Error(t, parsedObject.Set("capturedArgs[0]", parsedObject))
+	assert.NoError(t, parsedObject.Set("capturedArgs[0]", "EUR"))
+}
@@ -0,0 +1,105 @@
+package test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/maPaydar/gava-deserializer"
+	"github.com/maPaydar/gava-deserializer/pkg"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncodeParsed(t *testing.T) {
+	hexB := "aced00057372002f696d2e6163746f722e7365727665722e6469616c6f672e47726f75704469616c6f675374617465536e617073686f74000000000000000002000449000767726f757049644c000f6c6173744d657373616765446174657400134c6a6176612f74696d652f496e7374616e743b4c000c6c617374526561644461746571007e00014c000f6c617374526563656976654461746571007e00017870000000007372000d6a6176612e74696d652e536572955d84ba1b2248b20c00007870770d02000000005b1bd9bb352ad700787371007e0003770d02000000005b056ab729f63000787371007e0003770d02000000005b1bd9bb352ad70078"
+	data := pkg.DecodeHex(hexB)
+
+	parsedObject, err := gava.NewGavaDeserilizer(data).Decode()
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	assert.NoError(t, gava.NewEncoder(&buf).Encode(parsedObject))
+	assert.Equal(t, data, buf.Bytes())
+}
+
+func TestEncodeProxy(t *testing.T) {
+	data := pkg.DecodeHex(proxyHex)
+	parsedObject, err := gava.NewGavaDeserilizer(data).Decode()
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	assert.NoError(t, gava.NewEncoder(&buf).Encode(parsedObject))
+	assert.Equal(t, data, buf.Bytes())
+}
+
+func TestEncodeObject(t *testing.T) {
+	name := &gava.JavaString{Value: "café ☕"}
+	point := &gava.ClassDetails{
+		ClassName:        "com.acme.Point",
+		SerialVersionUID: 1,
+		ClassDescFlags:   0x02,
+		FieldDescription: []*gava.ClassField{
+			{TypeCode: 'I', Name: "x", Data: int32(3)},
+			{TypeCode: 'J', Name: "y", Data: int64(-4)},
+			{TypeCode: 'L', Name: "label", Data: name},
+			{TypeCode: 'L', Name: "name", Data: name},
+			{TypeCode: '[', Name: "tags", Data: &gava.JavaArray{ClassName: "[B", Elements: []interface{}{int8(1), int8(2)}}},
+		},
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(t, gava.NewEncoder(&buf).Encode(point))
+
+	parsedObject, err := gava.NewGavaDeserilizer(buf.Bytes()).Decode()
+	assert.NoError(t, err)
+	assert.Equal(t, "com.acme.Point", parsedObject.ClassName)
+	assert.Equal(t, int64(1), parsedObject.SerialVersionUID)
+	assert.Equal(t, int32(3), parsedObject.Field("x").Data)
+	assert.Equal(t, int64(-4), parsedObject.Field("y").Data)
+	assert.Equal(t, "café ☕", parsedObject.Field("label").Value)
+	assert.Same(t, parsedObject.Field("label").Data, parsedObject.Field("name").Data)
+	assert.Equal(t, "[1, 2]", parsedObject.Field("tags").Value)
+
+	assert.Error(t, gava.NewEncoder(&buf).Encode(&gava.ClassDetails{
+		ClassName:        "com.acme.Broken",
+		ClassDescFlags:   0x02,
+		FieldDescription: []*gava.ClassField{{TypeCode: 'I', Name: "x", Data: "three"}},
+	}))
+}
+
+func TestEncoderReset(t *testing.T) {
+	s := &gava.JavaString{Value: "hello"}
+
+	var buf bytes.Buffer
+	e := gava.NewEncoder(&buf)
+	assert.NoError(t, e.Encode(s))
+	assert.NoError(t, e.Encode(s))
+	assert.NoError(t, e.Reset())
+	assert.NoError(t, e.Encode(s))
+
+	assert.Equal(t, pkg.DecodeHex("aced000574000568656c6c6f71007e00007974000568656c6c6f"), buf.Bytes())
+}
+
+func TestEncodeFailure(t *testing.T) {
+	shared := gava.String("shared")
+	entry := func(v interface{}) *gava.ClassDetails {
+		return &gava.ClassDetails{ClassName: "com.acme.Entry", SerialVersionUID: 1, ClassDescFlags: 0x02,
+			FieldDescription: []*gava.ClassField{{TypeCode: 'L', Name: "value", Data: v}}}
+	}
+	var buf bytes.Buffer
+	e := gava.NewEncoder(&buf)
+
+	//Nothing of a value that can't be encoded is written or referred to afterwards
+	err := e.Encode(&gava.JavaArray{ClassName: "[Ljava.lang.Object;", Elements: []interface{}{shared, make(chan int)}})
+	assert.EqualError(t, err, "gava: cannot encode chan int")
+	assert.Equal(t, 0, buf.Len())
+	assert.NoError(t, e.Encode(shared))
+	assert.Equal(t, pkg.DecodeHex("aced0005740006736861726564"), buf.Bytes())
+
+	assert.Error(t, e.Encode(entry(make(chan int))))
+	assert.NoError(t, e.Encode(entry(shared)))
+	doc, err := gava.ParseDocument(buf.Bytes())
+	assert.NoError(t, err)
+	if assert.Len(t, doc.Contents, 2) {
+		assert.Equal(t, "shared", doc.Contents[1].(*gava.ClassDetails).Field("value").Value)
+	}
+}
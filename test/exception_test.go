@@ -0,0 +1,32 @@
+package test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/maPaydar/gava-deserializer"
+	"github.com/maPaydar/gava-deserializer/pkg"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRMIExceptionalReturn(t *testing.T) {
+	hexB := "51aced0005770f0200000000000000000000000000007372001a6a6176612e6c616e672e52756e74696d65457863657074696f6e9e5f06470a3483e5020000787200136a6176612e6c616e672e457863657074696f6ed0fd1f3e1a3b1cc4020000787200136a6176612e6c616e672e5468726f7761626c65d5c635273977b8cb0300044c000563617573657400154c6a6176612f6c616e672f5468726f7761626c653b4c000d64657461696c4d6573736167657400124c6a6176612f6c616e672f537472696e673b5b000a737461636b547261636574001e5b4c6a6176612f6c616e672f537461636b5472616365456c656d656e743b4c001473757070726573736564457863657074696f6e737400104c6a6176612f7574696c2f4c6973743b7870737200136a6176612e696f2e494f457863657074696f6e6c8073646525f0ab0200007871007e000171007e00097400096469736b2066756c6c7572001e5b4c6a6176612e6c616e672e537461636b5472616365456c656d656e743b2246c53c3cfd22390200007870000000027372001b6a6176612e6c616e672e537461636b5472616365456c656d656e746109c59a2636dd8502000449000a6c696e654e756d6265724c000e6465636c6172696e67436c6173737400124c6a6176612f6c616e672f537472696e673b4c000866696c654e616d657400124c6a6176612f6c616e672f537472696e673b4c000a6d6574686f644e616d657400124c6a6176612f6c616e672f537472696e673b78700000000374000d636f6d2e61636d652e4469736b7400094469736b2e6a61766174000577726974657371007e000d0000000774000d636f6d2e61636d652e4d61696e7400094d61696e2e6a6176617400046d61696e737200326a6176612e7574696c2e436f6c6c656374696f6e7324556e6d6f6469666961626c6552616e646f6d4163636573734c697374dcb7e7951f48464f020000787200266a6176612e7574696c2e436f6c6c656374696f6e7324556e6d6f6469666961626c654c697374fc0f2531b5ec8e100200014c00046c6973747400104c6a6176612f7574696c2f4c6973743b7872002c6a6176612e7574696c2e436f6c6c656374696f6e7324556e6d6f6469666961626c65436f6c6c656374696f6e19420080cb5ef71e0200014c0001637400164c6a6176612f7574696c2f436f6c6c656374696f6e3b7870737200136a6176612e7574696c2e41727261794c6973747881d21d99c7619d03000149000473697a657870000000007704000000007871007e002078740004626f6f6d7571007e000b000000027371007e000d0000002a740010636f6d2e61636d652e5365727669636574000c536572766963652e6a61766174000463616c6c7371007e000d0000000774000d636f6d2e61636d652e4d61696e7400094d61696e2e6a6176617400046d61696e71007e001e78"
+
+	g := gava.NewGavaDeserilizer(pkg.DecodeHex(hexB))
+	parsedObject := g.Parse()
+
+	assert.NotNil(t, parsedObject)
+	err := g.Exception()
+	assert.EqualError(t, err, "java.lang.RuntimeException: boom")
+
+	var e *gava.JavaException
+	assert.True(t, errors.As(errors.Unwrap(err), &e))
+	assert.Equal(t, "java.io.IOException", e.ClassName)
+	assert.Nil(t, e.Cause)
+	assert.Equal(t, "java.lang.RuntimeException: boom\n"+
+		"\tat com.acme.Service.call(Service.java:42)\n"+
+		"\tat com.acme.Main.main(Main.java:7)\n"+
+		"Caused by: java.io.IOException: disk full\n"+
+		"\tat com.acme.Disk.write(Disk.java:3)\n"+
+		"\t... 1 more\n", err.(*gava.JavaException).StackTraceString())
+}
@@ -0,0 +1,82 @@
+package test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/maPaydar/gava-deserializer"
+	"github.com/maPaydar/gava-deserializer/pkg"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFilter(t *testing.T) {
+	hexB := "aced00057372002f696d2e6163746f722e7365727665722e6469616c6f672e47726f75704469616c6f675374617465536e617073686f74000000000000000002000449000767726f757049644c000f6c6173744d657373616765446174657400134c6a6176612f74696d652f496e7374616e743b4c000c6c617374526561644461746571007e00014c000f6c617374526563656976654461746571007e00017870000000007372000d6a6176612e74696d652e536572955d84ba1b2248b20c00007870770d02000000005b1bd9bb352ad700787371007e0003770d02000000005b056ab729f63000787371007e0003770d02000000005b1bd9bb352ad70078"
+	data := pkg.DecodeHex(hexB)
+
+	for _, test := range []struct {
+		pattern string
+		class   string
+	}{
+		{"maxdepth=10;im.actor.**;java.base/java.time.*;!*", ""},
+		{"im.actor.**;java.time.Ser", ""},
+		{"im.actor.server.*;!*", "im.actor.server.dialog.GroupDialogStateSnapshot"},
+		{"!java.time.*;*", "java.time.Ser"},
+		{"maxdepth=1", "java.time.Ser"},
+		{"maxrefs=4", "java.time.Ser"},
+	} {
+		filter, err := gava.ParseFilter(test.pattern)
+		assert.NoError(t, err)
+		_, err = gava.DecoderOptions{Filter: filter}.Decode(data)
+		if test.class == "" {
+			assert.NoError(t, err, test.pattern)
+			continue
+		}
+		var filterErr *gava.FilterError
+		assert.True(t, errors.As(err, &filterErr), "%s: %v", test.pattern, err)
+		if filterErr != nil {
+			assert.Equal(t, test.class, filterErr.Info.Class, test.pattern)
+		}
+	}
+
+	//Arrays of primitives are left undecided
+	filter, err := gava.ParseFilter("Test;!*")
+	assert.NoError(t, err)
+	_, err = gava.DecoderOptions{Filter: filter}.Decode([]byte(readLine("./test.txt")))
+	assert.NoError(t, err)
+	filter, err = gava.ParseFilter("maxarray=3")
+	assert.NoError(t, err)
+	_, err = gava.DecoderOptions{Filter: filter}.Decode([]byte(readLine("./test.txt")))
+	assert.EqualError(t, err, "gava: filter rejected [B of length 4 (offset 95)")
+
+	for _, pattern := range []string{"maxobjects=1", "maxdepth=x", "com.*.Foo", "!"} {
+		_, err := gava.ParseFilter(pattern)
+		assert.Error(t, err, pattern)
+	}
+}
+
+func TestInputFilterCheckpoints(t *testing.T) {
+	var infos []gava.FilterInfo
+	opts := gava.DecoderOptions{Filter: func(info gava.FilterInfo) gava.FilterStatus {
+		infos = append(infos, info)
+		return gava.FilterUndecided
+	}}
+	_, err := opts.Decode([]byte(readLine("./test.txt")))
+	assert.NoError(t, err)
+
+	assert.Equal(t, []gava.FilterInfo{
+		{Class: "Test", ArrayLength: -1, Depth: 1, References: 0, StreamBytes: 12},
+		{Class: "[B", ArrayLength: -1, Depth: 2, References: 5, StreamBytes: 78},
+		{Class: "[B", ArrayLength: 4, Depth: 2, References: 7, StreamBytes: 95},
+	}, infos)
+}
+
+func TestFilterProxyInterfaces(t *testing.T) {
+	filter, err := gava.ParseFilter("!java.lang.Runnable")
+	assert.NoError(t, err)
+	_, err = gava.DecoderOptions{Filter: filter}.Decode(pkg.DecodeHex(proxyHex))
+	var filterErr *gava.FilterError
+	assert.True(t, errors.As(err, &filterErr), "%v", err)
+	if filterErr != nil {
+		assert.Equal(t, "java.lang.Runnable", filterErr.Info.Class)
+	}
+}
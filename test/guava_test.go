@@ -0,0 +1,133 @@
+package test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/maPaydar/gava-deserializer"
+	"github.com/maPaydar/gava-deserializer/pkg"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGuavaAndCommonsCollections(t *testing.T) {
+	hexB := "aced000573720011636f6d2e61636d652e526573706f6e736500000000000000010200034c0005617474727374000f4c6a6176612f7574696c2f4d61703b4c0005696e64657874000f4c6a6176612f7574696c2f4d61703b4c0004746167737400104c6a6176612f7574696c2f4c6973743b78707372002c6f72672e6170616368652e636f6d6d6f6e732e636f6c6c656374696f6e73342e6d61702e466c6174334d6170a300f47ee17184980300007870770400000001740006726567696f6e74000265757873720035636f6d2e676f6f676c652e636f6d6d6f6e2e636f6c6c6563742e496d6d757461626c654d61702453657269616c697a6564466f726d00000000000000000200024c00046b6579737400124c6a6176612f6c616e672f4f626a6563743b4c000676616c7565737400124c6a6176612f6c616e672f4f626a6563743b7870757200135b4c6a6176612e6c616e672e4f626a6563743b90ce589f1073296c02000078700000000274000161740001627571007e000d00000002737200116a6176612e6c616e672e496e746567657212e2a0a4f781873802000149000576616c7565787200106a6176612e6c616e672e4e756d62657286ac951d0b94e08b0200007870000000017371007e00120000000273720036636f6d2e676f6f676c652e636f6d6d6f6e2e636f6c6c6563742e496d6d757461626c654c6973742453657269616c697a6564466f726d00000000000000000200015b0008656c656d656e74737400135b4c6a6176612f6c616e672f4f626a6563743b78707571007e000d000000017400036e6577"
+
+	parsedObject := gava.NewGavaDeserilizer(pkg.DecodeHex(hexB)).Parse()
+
+	assert.NotNil(t, parsedObject)
+	assert.Equal(t, map[interface{}]interface{}{"region": "eu"}, parsedObject.Field("attrs").Data.(*gava.ClassDetails).Decoded)
+	assert.Equal(t, map[interface{}]interface{}{"a": int32(1), "b": int32(2)}, parsedObject.Field("index").Data.(*gava.ClassDetails).Decoded)
+	assert.Equal(t, []interface{}{"new"}, parsedObject.Field("tags").Data.(*gava.ClassDetails).Decoded)
+}
+
+// objectData lays out values the way ObjectOutputStream writes them from writeObject: ints
+// and floats go to block data, objects between the blocks.
+func objectData(values ...interface{}) []interface{} {
+	var data []interface{}
+	var block gava.BlockData
+	for _, v := range values {
+		var b [4]byte
+		switch v := v.(type) {
+		case int:
+			binary.BigEndian.PutUint32(b[:], uint32(v))
+			block = append(block, b[:]...)
+		case float32:
+			binary.BigEndian.PutUint32(b[:], math.Float32bits(v))
+			block = append(block, b[:]...)
+		default:
+			if block != nil {
+				data = append(data, block)
+				block = nil
+			}
+			data = append(data, v)
+		}
+	}
+	if block != nil {
+		data = append(data, block)
+	}
+	return data
+}
+
+func collection(class string, fields []*gava.ClassField, data ...interface{}) *gava.ClassDetails {
+	obj := &gava.ClassDetails{ClassName: class, SerialVersionUID: 1, ClassDescFlags: 0x02, FieldDescription: fields}
+	if data != nil {
+		obj.ClassDescFlags |= 0x01
+		obj.Annotation = objectData(data...)
+	}
+	return obj
+}
+
+// enumConstants replaces the enums of a decoded set or map by their constants.
+func enumConstants(v interface{}) interface{} {
+	switch v := v.(type) {
+	case []interface{}:
+		constants := []interface{}{}
+		for _, e := range v {
+			constants = append(constants, enumConstants(e))
+		}
+		return constants
+	case map[interface{}]interface{}:
+		constants := map[interface{}]interface{}{}
+		for k, e := range v {
+			constants[enumConstants(k)] = e
+		}
+		return constants
+	case *gava.JavaEnum:
+		return v.Constant
+	}
+	return v
+}
+
+func TestGuavaForms(t *testing.T) {
+	s := gava.String
+	objects := func(elements ...interface{}) *gava.JavaArray {
+		return &gava.JavaArray{ClassName: "[Ljava.lang.Object;", Elements: elements}
+	}
+	color := func(constant string) *gava.JavaEnum {
+		return &gava.JavaEnum{ClassName: "com.acme.Color", Constant: constant}
+	}
+	enumSet := collection("java.util.EnumSet$SerializationProxy", []*gava.ClassField{
+		{TypeCode: 'L', Name: "elementType", Data: nil},
+		{TypeCode: '[', Name: "elements", Data: &gava.JavaArray{ClassName: "[Ljava.lang.Enum;",
+			Elements: []interface{}{color("RED"), color("GREEN")}}},
+	})
+	enumMap := collection("java.util.EnumMap", []*gava.ClassField{{TypeCode: 'L', Name: "keyType", Data: nil}},
+		2, color("RED"), s("stop"), color("GREEN"), s("go"))
+	delegate := func(v interface{}) []*gava.ClassField {
+		return []*gava.ClassField{{TypeCode: 'L', Name: "delegate", Data: v}}
+	}
+
+	for _, test := range []struct {
+		obj     *gava.ClassDetails
+		decoded interface{}
+	}{
+		{collection("com.google.common.collect.ArrayListMultimap", nil, 2, s("a"), 2, s("x"), s("y"), s("b"), 1, s("z")),
+			map[interface{}]interface{}{"a": []interface{}{"x", "y"}, "b": []interface{}{"z"}}},
+		//The value comparator comes first
+		{collection("com.google.common.collect.ImmutableSetMultimap", nil, nil, 1, s("a"), 1, s("x")),
+			map[interface{}]interface{}{"a": []interface{}{"x"}}},
+		{collection("com.google.common.collect.LinkedListMultimap", nil, 3, s("a"), s("x"), s("b"), s("y"), s("a"), s("z")),
+			map[interface{}]interface{}{"a": []interface{}{"x", "z"}, "b": []interface{}{"y"}}},
+		{collection("com.google.common.collect.HashMultiset", nil, 2, s("a"), 2, s("b"), 1),
+			map[interface{}]interface{}{"a": int32(2), "b": int32(1)}},
+		{collection("com.google.common.collect.ImmutableSet$SerializedForm",
+			[]*gava.ClassField{{TypeCode: 'L', Name: "elements", Data: objects(s("a"), s("b"))}}),
+			[]interface{}{"a", "b"}},
+		{collection("org.apache.commons.collections4.map.LinkedMap", nil, float32(0.75), 16, 2, s("a"), s("x"), s("b"), s("y")),
+			map[interface{}]interface{}{"a": "x", "b": "y"}},
+		{enumSet, []interface{}{"RED", "GREEN"}},
+		{collection("com.google.common.collect.ImmutableEnumSet$EnumSerializedForm", delegate(enumSet)),
+			[]interface{}{"RED", "GREEN"}},
+		{enumMap, map[interface{}]interface{}{"RED": "stop", "GREEN": "go"}},
+		{collection("com.google.common.collect.ImmutableEnumMap$EnumSerializedForm", delegate(enumMap)),
+			map[interface{}]interface{}{"RED": "stop", "GREEN": "go"}},
+	} {
+		var buf bytes.Buffer
+		assert.NoError(t, gava.NewEncoder(&buf).Encode(test.obj))
+		obj, err := gava.NewGavaDeserilizer(buf.Bytes()).Decode()
+		assert.NoError(t, err)
+		assert.Equal(t, test.decoded, enumConstants(obj.Decoded), test.obj.ClassName)
+	}
+}
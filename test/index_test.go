@@ -0,0 +1,141 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/maPaydar/gava-deserializer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIndex(t *testing.T) {
+	data := []byte(readLine("./test.txt"))
+	x, err := gava.NewIndex(data)
+	assert.NoError(t, err)
+
+	var classes []string
+	for _, e := range x.Entries {
+		classes = append(classes, e.Class)
+	}
+	//The strings are the field type of a and its value
+	assert.Equal(t, []string{"Test", "java.lang.String", "java.lang.String", "java.lang.String", "[B"}, classes)
+	test := x.Entries[0]
+	assert.True(t, test.TopLevel)
+	assert.False(t, x.Entries[1].TopLevel)
+	assert.Equal(t, gava.Span{Offset: 4, Length: len(data) - 4}, test.Span)
+	assert.Equal(t, 0x7e0003, test.Handle)
+
+	a, err := x.Get("a")
+	assert.NoError(t, err)
+	assert.Equal(t, "aa", a.(*gava.JavaString).Value)
+	c, err := x.Get("c[1]")
+	assert.NoError(t, err)
+	assert.Equal(t, int8(2), c)
+	_, err = x.Get("d")
+	assert.Error(t, err)
+
+	//Values are the ones ParseDocument decodes, each decoded once
+	doc, err := gava.ParseDocument(data)
+	assert.NoError(t, err)
+	v, err := x.Value(test)
+	assert.NoError(t, err)
+	assert.Equal(t, doc.Object().Decoded, v.(*gava.ClassDetails).Decoded)
+	assert.Equal(t, jsonString(t, doc.Object()), jsonString(t, v))
+	same, err := x.Handle(0x7e0003)
+	assert.NoError(t, err)
+	assert.Same(t, v, same)
+	assert.Len(t, x.Class("java.lang.String"), 3)
+	array, err := x.Value(x.Class("[B")[0])
+	assert.NoError(t, err)
+	assert.Same(t, v.(*gava.ClassDetails).Field("c").Data, array)
+
+	_, err = gava.NewIndex(data[:len(data)-1])
+	assert.Error(t, err)
+}
+
+func jsonString(t *testing.T, v interface{}) string {
+	s, err := json.Marshal(v)
+	assert.NoError(t, err)
+	return string(s)
+}
+
+func TestIndexReferences(t *testing.T) {
+	inner := annotated("com.acme.Inner", 0x02)
+	outer := &gava.ClassDetails{
+		ClassName:        "com.acme.Outer",
+		SerialVersionUID: 1,
+		ClassDescFlags:   0x02,
+		FieldDescription: []*gava.ClassField{
+			{TypeCode: 'L', Name: "inner", Data: inner},
+			{TypeCode: '[', Name: "names", Data: &gava.JavaArray{ClassName: "[Ljava.lang.String;", Elements: []interface{}{gava.String("alice"), nil}}},
+		},
+		SuperClass: annotated("com.acme.Base", 0x03, gava.BlockData{1, 2}, inner),
+	}
+	var buf bytes.Buffer
+	e := gava.NewEncoder(&buf)
+	assert.NoError(t, e.Encode(outer))
+	assert.NoError(t, e.Reset())
+	assert.NoError(t, e.Encode(inner))
+	data := buf.Bytes()
+
+	x, err := gava.NewIndex(data)
+	assert.NoError(t, err)
+	inners := x.Class("com.acme.Inner")
+	assert.Len(t, inners, 2)
+	assert.False(t, inners[0].TopLevel)
+	assert.True(t, inners[1].TopLevel)
+	assert.Equal(t, 0, inners[0].Reset)
+	assert.Equal(t, 1, inners[1].Reset)
+
+	//The field refers to the object in the annotations of the super class
+	name, err := x.Get("names[0]")
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", name.(*gava.JavaString).Value)
+	id, err := x.Get("inner.id")
+	assert.NoError(t, err)
+	assert.Equal(t, int32(7), id)
+	v, err := x.Get("inner")
+	assert.NoError(t, err)
+	first, err := x.Value(inners[0])
+	assert.NoError(t, err)
+	assert.Same(t, first, v)
+	o, err := x.Value(x.Class("com.acme.Outer")[0])
+	assert.NoError(t, err)
+	assert.Same(t, first, o.(*gava.ClassDetails).Field("inner").Data)
+	assert.Same(t, first, o.(*gava.ClassDetails).SuperClass.Annotation[1])
+
+	//Handles are assigned again after a reset
+	second, err := x.Value(inners[1])
+	assert.NoError(t, err)
+	assert.NotSame(t, first, second)
+	assert.Equal(t, inners[1].Handle, second.(*gava.ClassDetails).RefHandle)
+	_, err = x.Get("names[2]")
+	assert.Error(t, err)
+}
+
+func TestIndexCycle(t *testing.T) {
+	parent := transformer("com.acme.Node", &gava.ClassField{TypeCode: 'L', Name: "next"})
+	child := transformer("com.acme.Node", &gava.ClassField{TypeCode: 'L', Name: "next", Data: parent})
+	parent.FieldDescription[0].Data = child
+	var buf bytes.Buffer
+	assert.NoError(t, gava.NewEncoder(&buf).Encode(parent))
+
+	x, err := gava.NewIndex(buf.Bytes())
+	assert.NoError(t, err)
+	nodes := x.Class("com.acme.Node")
+	assert.Len(t, nodes, 2)
+	//The child refers back to its parent, which is decoded with it
+	c, err := x.Value(nodes[1])
+	assert.NoError(t, err)
+	p, err := x.Value(nodes[0])
+	assert.NoError(t, err)
+	assert.Same(t, p, c.(*gava.ClassDetails).Field("next").Data)
+	assert.Same(t, c, p.(*gava.ClassDetails).Field("next").Data)
+	next, err := x.Get("next.next")
+	assert.NoError(t, err)
+	assert.Same(t, p, next)
+
+	_, err = gava.DecoderOptions{MaxObjects: 2}.NewIndex(buf.Bytes())
+	assert.ErrorIs(t, err, gava.ErrMaxObjects)
+}
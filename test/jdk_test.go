@@ -0,0 +1,172 @@
+package test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"net/url"
+	"regexp"
+	"testing"
+
+	"github.com/maPaydar/gava-deserializer"
+	"github.com/maPaydar/gava-deserializer/pkg"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUUID(t *testing.T) {
+	hexB := "aced00057372000e6a6176612e7574696c2e55554944bc9903f7986d852f0200024a000c6c65617374536967426974734a000b6d6f7374536967426974737870a594837261f0e5d5123e4567e89b12d3"
+
+	parsedObject := gava.NewGavaDeserilizer(pkg.DecodeHex(hexB)).Parse()
+
+	assert.NotNil(t, parsedObject)
+	u, ok := parsedObject.Decoded.(gava.UUID)
+	assert.True(t, ok)
+	assert.Equal(t, "123e4567-e89b-12d3-a594-837261f0e5d5", u.String())
+}
+
+func TestLocale(t *testing.T) {
+	hexB := "aced0005737200106a6176612e7574696c2e4c6f63616c657ef811609c30f9ec03000649000868617368636f64654c0007636f756e7472797400124c6a6176612f6c616e672f537472696e673b4c000a657874656e73696f6e7371007e00014c00086c616e677561676571007e00014c000673637269707471007e00014c000776617269616e7471007e00017870ffffffff7400025553740000740002656e71007e000471007e000478"
+
+	parsedObject := gava.NewGavaDeserilizer(pkg.DecodeHex(hexB)).Parse()
+
+	assert.NotNil(t, parsedObject)
+	assert.Equal(t, "en-US", parsedObject.Decoded)
+}
+
+func TestInet6Address(t *testing.T) {
+	hexB := "aced0005737200156a6176612e6e65742e496e657436416464726573735f7c2081522c802103000549000873636f70655f69645a000c73636f70655f69645f7365745a001073636f70655f69666e616d655f7365744c000669666e616d657400124c6a6176612f6c616e672f537472696e673b5b00096970616464726573737400025b42787200146a6176612e6e65742e496e6574416464726573732d9b57af9fe3ebdb0300034900076164647265737349000666616d696c794c0008686f73744e616d657400124c6a6176612f6c616e672f537472696e673b787000000000000000027400096c6f63616c686f73747800000000000070757200025b42acf317f8060854e00200007870000000100000000000000000000000000000000178"
+
+	parsedObject := gava.NewGavaDeserilizer(pkg.DecodeHex(hexB)).Parse()
+
+	assert.NotNil(t, parsedObject)
+	assert.Equal(t, net.IPv6loopback, parsedObject.Decoded)
+	assert.Equal(t, "localhost", parsedObject.Field("hostName").Value)
+}
+
+func TestInet6AddressUndecoded(t *testing.T) {
+	//An Inet6Address with a null ipaddress and the IPv6 family
+	hexB := "aced0005737200156a6176612e6e65742e496e657436416464726573735f7c2081522c802103000549000873636f70655f69645a000c73636f70655f69645f7365745a001073636f70655f69666e616d655f7365744c000669666e616d657400124c6a6176612f6c616e672f537472696e673b5b00096970616464726573737400025b42787200146a6176612e6e65742e496e6574416464726573732d9b57af9fe3ebdb0300034900076164647265737349000666616d696c794c0008686f73744e616d657400124c6a6176612f6c616e672f537472696e673b787000000000000000027400096c6f63616c686f737478000000000000707078"
+
+	parsedObject, err := gava.NewGavaDeserilizer(pkg.DecodeHex(hexB)).Decode()
+	assert.NoError(t, err)
+	assert.Nil(t, parsedObject.Decoded)
+	assert.Equal(t, "localhost", parsedObject.Field("hostName").Value)
+
+	doc, err := gava.DecoderOptions{Lenient: true}.ParseDocument(pkg.DecodeHex(hexB))
+	assert.NoError(t, err)
+	if assert.Len(t, doc.Diagnostics, 1) {
+		assert.Equal(t, gava.DiagnosticWarning, doc.Diagnostics[0].Severity)
+		assert.EqualError(t, doc.Diagnostics[0].Err, "cannot decode java.net.Inet6Address: unsupported address family 2")
+	}
+}
+
+func jdkObject(class string, fields ...*gava.ClassField) *gava.ClassDetails {
+	return &gava.ClassDetails{ClassName: class, SerialVersionUID: 1, ClassDescFlags: 0x02, FieldDescription: fields}
+}
+
+func inet4Address(family int32) *gava.ClassDetails {
+	obj := jdkObject("java.net.Inet4Address")
+	obj.SuperClass = jdkObject("java.net.InetAddress",
+		&gava.ClassField{TypeCode: 'L', Name: "hostName", Data: gava.String("localhost")},
+		&gava.ClassField{TypeCode: 'I', Name: "address", Data: int32(0x7f000001)},
+		&gava.ClassField{TypeCode: 'I', Name: "family", Data: family})
+	return obj
+}
+
+func TestJDKValues(t *testing.T) {
+	for _, test := range []struct {
+		obj     *gava.ClassDetails
+		decoded string
+		err     string
+	}{
+		{obj: jdkObject("java.net.URI",
+			&gava.ClassField{TypeCode: 'L', Name: "string", Data: gava.String("https://example.com/a?b=c#d")}),
+			decoded: "https://example.com/a?b=c#d"},
+		{obj: jdkObject("java.net.URI",
+			&gava.ClassField{TypeCode: 'L', Name: "string", Data: gava.String("http://%zz")}),
+			err: `cannot decode java.net.URI: parse "http://%zz": invalid URL escape "%zz"`},
+		{obj: jdkObject("java.net.URI"), err: "cannot decode java.net.URI: missing field string"},
+		{obj: jdkObject("java.net.URL",
+			&gava.ClassField{TypeCode: 'L', Name: "protocol", Data: gava.String("http")},
+			&gava.ClassField{TypeCode: 'L', Name: "authority", Data: gava.String("example.com:8080")},
+			&gava.ClassField{TypeCode: 'L', Name: "file", Data: gava.String("/index.html")},
+			&gava.ClassField{TypeCode: 'L', Name: "ref", Data: gava.String("top")}),
+			decoded: "http://example.com:8080/index.html#top"},
+		{obj: jdkObject("java.net.URL", &gava.ClassField{TypeCode: 'L', Name: "protocol", Data: gava.String("file")}),
+			err: "cannot decode java.net.URL: missing field authority"},
+		{obj: jdkObject("java.io.File", &gava.ClassField{TypeCode: 'L', Name: "path", Data: gava.String("/tmp/a.txt")}),
+			decoded: "/tmp/a.txt"},
+		{obj: jdkObject("java.util.Currency", &gava.ClassField{TypeCode: 'L', Name: "currencyCode", Data: gava.String("EUR")}),
+			decoded: "EUR"},
+		{obj: jdkObject("java.util.Currency", &gava.ClassField{TypeCode: 'I', Name: "currencyCode", Data: int32(978)}),
+			err: "cannot decode java.util.Currency: field currencyCode is int32, not String"},
+		{obj: inet4Address(1), decoded: "127.0.0.1"},
+		{obj: inet4Address(3), err: "cannot decode java.net.Inet4Address: unsupported address family 3"},
+		{obj: jdkObject("java.util.regex.Pattern",
+			&gava.ClassField{TypeCode: 'L', Name: "pattern", Data: gava.String("a+b")},
+			&gava.ClassField{TypeCode: 'I', Name: "flags", Data: int32(0x02)}),
+			decoded: "(?i)a+b"},
+		//Lookbehinds aren't supported by RE2, the pattern is left undecoded
+		{obj: jdkObject("java.util.regex.Pattern",
+			&gava.ClassField{TypeCode: 'L', Name: "pattern", Data: gava.String("(?<=a)b")},
+			&gava.ClassField{TypeCode: 'I', Name: "flags", Data: int32(0)})},
+		//LITERAL and COMMENTS change what the pattern matches and have no RE2 flag
+		{obj: jdkObject("java.util.regex.Pattern",
+			&gava.ClassField{TypeCode: 'L', Name: "pattern", Data: gava.String("a+b")},
+			&gava.ClassField{TypeCode: 'I', Name: "flags", Data: int32(0x10)})},
+		{obj: jdkObject("java.util.regex.Pattern",
+			&gava.ClassField{TypeCode: 'L', Name: "pattern", Data: gava.String("a b # c")},
+			&gava.ClassField{TypeCode: 'I', Name: "flags", Data: int32(0x04)})},
+	} {
+		var buf bytes.Buffer
+		assert.NoError(t, gava.NewEncoder(&buf).Encode(test.obj))
+
+		doc, err := gava.DecoderOptions{Lenient: true}.ParseDocument(buf.Bytes())
+		assert.NoError(t, err)
+		obj := doc.Object()
+		if test.err != "" {
+			assert.Nil(t, obj.Decoded, test.err)
+			if assert.Len(t, doc.Diagnostics, 1, test.err) {
+				assert.EqualError(t, doc.Diagnostics[0].Err, test.err)
+			}
+			continue
+		}
+		assert.Empty(t, doc.Diagnostics, test.decoded)
+		if test.decoded == "" {
+			assert.Nil(t, obj.Decoded, obj.ClassName)
+			continue
+		}
+		switch v := obj.Decoded.(type) {
+		case *url.URL:
+			assert.Equal(t, test.decoded, v.String())
+		case net.IP:
+			assert.Equal(t, test.decoded, v.String())
+		case *regexp.Regexp:
+			assert.Equal(t, test.decoded, v.String())
+		default:
+			assert.Equal(t, test.decoded, v)
+		}
+	}
+}
+
+func TestTruncatedStream(t *testing.T) {
+	for _, obj := range []*gava.ClassDetails{
+		inet4Address(1),
+		annotated("com.acme.Block", 0x03, gava.BlockData{1, 2, 3}, gava.String("kept")),
+	} {
+		var buf bytes.Buffer
+		assert.NoError(t, gava.NewEncoder(&buf).Encode(obj))
+		data := buf.Bytes()
+
+		//Every read is bounds checked, a stream ending before its object does fails with
+		//io.ErrUnexpectedEOF
+		for n := 0; n < len(data); n++ {
+			if n == 4 {
+				continue
+			}
+			_, err := gava.ParseDocument(data[:n])
+			assert.True(t, errors.Is(err, io.ErrUnexpectedEOF), "%d: %v", n, err)
+		}
+	}
+}
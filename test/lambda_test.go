@@ -0,0 +1,43 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/maPaydar/gava-deserializer"
+	"github.com/maPaydar/gava-deserializer/pkg"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSerializedLambda(t *testing.T) {
+	hexB := "aced0005737200216a6176612e6c616e672e696e766f6b652e53657269616c697a65644c616d6264616f61d0942c29368502000a49000e696d706c4d6574686f644b696e645b000c6361707475726564417267737400135b4c6a6176612f6c616e672f4f626a6563743b4c000e636170747572696e67436c6173737400114c6a6176612f6c616e672f436c6173733b4c001866756e6374696f6e616c496e74657266616365436c6173737400124c6a6176612f6c616e672f537472696e673b4c001d66756e6374696f6e616c496e746572666163654d6574686f644e616d6571007e00034c002266756e6374696f6e616c496e746572666163654d6574686f645369676e617475726571007e00034c0009696d706c436c61737371007e00034c000e696d706c4d6574686f644e616d6571007e00034c0013696d706c4d6574686f645369676e617475726571007e00034c0016696e7374616e7469617465644d6574686f645479706571007e0003787000000006757200135b4c6a6176612e6c616e672e4f626a6563743b90ce589f1073296c0200007870000000017400034555527672000f636f6d2e61636d652e4f72646572730000000000000001020000787074001c6a6176612f7574696c2f66756e6374696f6e2f50726564696361746574000474657374740015284c6a6176612f6c616e672f4f626a6563743b295a74000f636f6d2f61636d652f4f726465727374000d6c616d626461246f70656e2430740025284c6a6176612f6c616e672f537472696e673b4c636f6d2f61636d652f4f726465723b295a740013284c636f6d2f61636d652f4f726465723b295a"
+
+	parsedObject := gava.NewGavaDeserilizer(pkg.DecodeHex(hexB)).Parse()
+
+	assert.NotNil(t, parsedObject)
+	l, ok := parsedObject.Decoded.(*gava.Lambda)
+	assert.True(t, ok)
+	assert.Equal(t, "com.acme.Orders", l.CapturingClass)
+	assert.Equal(t, "com/acme/Orders", l.ImplClass)
+	assert.Equal(t, "lambda$open$0", l.ImplMethodName)
+	assert.Equal(t, "(Ljava/lang/String;Lcom/acme/Order;)Z", l.Signature)
+	assert.Equal(t, []interface{}{"EUR"}, l.CapturedArgs)
+}
+
+func TestRecord(t *testing.T) {
+	hexB := "aced000573720017636f6d2e61636d652e5265636f7264437573746f6d6572000000000000000002000249000269644c00046e616d657400124c6a6176612f6c616e672f537472696e673b787000000007740003416461"
+
+	parsedObject := gava.NewGavaDeserilizer(pkg.DecodeHex(hexB)).Parse()
+	assert.True(t, parsedObject.IsRecord())
+	assert.Nil(t, parsedObject.Decoded)
+
+	//The registry is global, so the class is only used by this test
+	gava.RegisterRecord("com.acme.RecordCustomer", "name", "id")
+	parsedObject = gava.NewGavaDeserilizer(pkg.DecodeHex(hexB)).Parse()
+	assert.Equal(t, &gava.Record{
+		ClassName: "com.acme.RecordCustomer",
+		Components: []gava.RecordComponent{
+			{Name: "name", Value: "Ada"},
+			{Name: "id", Value: int32(7)},
+		},
+	}, parsedObject.Decoded)
+}
@@ -0,0 +1,102 @@
+package test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/maPaydar/gava-deserializer"
+	"github.com/stretchr/testify/assert"
+)
+
+func annotated(class string, flags byte, annotation ...interface{}) *gava.ClassDetails {
+	return &gava.ClassDetails{
+		ClassName:        class,
+		SerialVersionUID: 1,
+		ClassDescFlags:   flags,
+		FieldDescription: []*gava.ClassField{{TypeCode: 'I', Name: "id", Data: int32(7)}},
+		Annotation:       annotation,
+	}
+}
+
+func TestLenientWarnings(t *testing.T) {
+	var buf bytes.Buffer
+	e := gava.NewEncoder(&buf)
+	assert.NoError(t, e.Encode(annotated("com.acme.Flags", 0x03, gava.String("first"), gava.String("second"))))
+	data := buf.Bytes()
+	//SC_SERIALIZABLE | SC_BLOCKDATA, and a reference to a handle that doesn't exist
+	flags := bytes.Index(data, []byte("com.acme.Flags")) + len("com.acme.Flags") + 8
+	data[flags] = 0x0b
+	second := bytes.Index(data, []byte("second")) - 3
+	copy(data[second:], []byte{0x71, 0x00, 0x7e, 0x00, 0x09, 0x78, 0x78})
+	data = data[:second+6]
+
+	_, err := gava.ParseDocument(data)
+	assert.Error(t, err)
+
+	doc, err := gava.DecoderOptions{Lenient: true}.ParseDocument(data)
+	assert.NoError(t, err)
+	obj := doc.Object()
+	assert.Equal(t, int32(7), obj.Field("id").Data)
+	assert.Len(t, obj.Annotation, 2)
+	assert.Equal(t, "first", obj.Annotation[0].(*gava.JavaString).Value)
+	assert.Nil(t, obj.Annotation[1])
+	assert.Len(t, doc.Diagnostics, 2)
+	assert.Equal(t, gava.DiagnosticWarning, doc.Diagnostics[0].Severity)
+	assert.Equal(t, flags+1, doc.Diagnostics[0].Offset)
+	assert.Contains(t, doc.Diagnostics[0].Err.Error(), "SC_BLOCKDATA")
+	assert.Equal(t, gava.DiagnosticWarning, doc.Diagnostics[1].Severity)
+	assert.Contains(t, doc.Diagnostics[1].String(), "Invalid reference handle (0x7e0009)")
+}
+
+func TestLenientResync(t *testing.T) {
+	var buf bytes.Buffer
+	e := gava.NewEncoder(&buf)
+	assert.NoError(t, e.Encode(annotated("com.acme.Block", 0x03, gava.BlockData{1, 2, 3}, gava.String("kept"))))
+	assert.NoError(t, e.Encode(gava.String("lost")))
+	assert.NoError(t, e.Reset())
+	assert.NoError(t, e.Encode(gava.String("after reset")))
+	data := buf.Bytes()
+	//An unknown content element in the annotation, and garbage at the top level
+	block := bytes.Index(data, []byte{0x77, 0x03, 0x01, 0x02, 0x03})
+	data[block] = 0x01
+	data[bytes.Index(data, []byte("lost"))-3] = 0x02
+
+	doc, err := gava.DecoderOptions{Lenient: true}.ParseDocument(data)
+	assert.NoError(t, err)
+	assert.Len(t, doc.Contents, 3)
+	obj := doc.Object()
+	assert.Equal(t, int32(7), obj.Field("id").Data)
+	assert.Nil(t, obj.Annotation)
+	assert.Equal(t, gava.Reset{}, doc.Contents[1])
+	assert.Equal(t, "after reset", doc.Contents[2].(*gava.JavaString).Value)
+
+	assert.Len(t, doc.Diagnostics, 2)
+	assert.Equal(t, gava.DiagnosticError, doc.Diagnostics[0].Severity)
+	assert.Equal(t, block, doc.Diagnostics[0].Offset)
+	assert.Equal(t, 5+3+len("kept"), doc.Diagnostics[0].Skipped)
+	assert.Equal(t, gava.DiagnosticError, doc.Diagnostics[1].Severity)
+	assert.Equal(t, 3+len("lost"), doc.Diagnostics[1].Skipped)
+}
+
+func TestLenientTruncated(t *testing.T) {
+	data := []byte(readLine("./test.txt"))
+	data = data[:len(data)-20]
+
+	_, err := gava.ParseDocument(data)
+	assert.True(t, errors.Is(err, io.ErrUnexpectedEOF))
+
+	//The object is kept with the fields read before the end
+	doc, err := gava.DecoderOptions{Lenient: true}.ParseDocument(data)
+	assert.NoError(t, err)
+	assert.Len(t, doc.Contents, 1)
+	assert.Equal(t, "Test", doc.Object().ClassName)
+	assert.Len(t, doc.Diagnostics, 1)
+	assert.Equal(t, gava.DiagnosticError, doc.Diagnostics[0].Severity)
+	assert.True(t, errors.Is(doc.Diagnostics[0].Err, io.ErrUnexpectedEOF))
+
+	//Limits aren't lenient
+	_, err = gava.DecoderOptions{Lenient: true, MaxObjects: 1}.ParseDocument(data)
+	assert.True(t, errors.Is(err, gava.ErrMaxObjects))
+}
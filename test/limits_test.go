@@ -0,0 +1,81 @@
+package test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/maPaydar/gava-deserializer"
+	"github.com/maPaydar/gava-deserializer/pkg"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecoderLimits(t *testing.T) {
+	hexB := "aced00057372002f696d2e6163746f722e7365727665722e6469616c6f672e47726f75704469616c6f675374617465536e617073686f74000000000000000002000449000767726f757049644c000f6c6173744d657373616765446174657400134c6a6176612f74696d652f496e7374616e743b4c000c6c617374526561644461746571007e00014c000f6c617374526563656976654461746571007e00017870000000007372000d6a6176612e74696d652e536572955d84ba1b2248b20c00007870770d02000000005b1bd9bb352ad700787371007e0003770d02000000005b056ab729f63000787371007e0003770d02000000005b1bd9bb352ad70078"
+	data := pkg.DecodeHex(hexB)
+
+	limits := gava.DecoderOptions{MaxDepth: 3, MaxStringLength: 47, MaxTotalBytes: len(data), MaxObjects: 7, MaxReferences: 4}
+	_, err := limits.Decode(data)
+	assert.NoError(t, err)
+
+	for _, test := range []struct {
+		opts gava.DecoderOptions
+		err  error
+	}{
+		{gava.DecoderOptions{MaxDepth: 2}, gava.ErrMaxDepth},
+		{gava.DecoderOptions{MaxStringLength: 46}, gava.ErrMaxStringLength},
+		{gava.DecoderOptions{MaxTotalBytes: len(data) - 1}, gava.ErrMaxTotalBytes},
+		{gava.DecoderOptions{MaxObjects: 6}, gava.ErrMaxObjects},
+		{gava.DecoderOptions{MaxReferences: 3}, gava.ErrMaxReferences},
+	} {
+		_, err := test.opts.Decode(data)
+		assert.True(t, errors.Is(err, test.err), "%+v: %v", test.opts, err)
+		_, err = test.opts.ParseDocument(data)
+		assert.True(t, errors.Is(err, test.err), "%+v: %v", test.opts, err)
+	}
+}
+
+func TestDecoderValueLength(t *testing.T) {
+	//A list of 40 nodes, the Value of its head would take terabytes
+	var head *gava.ClassDetails
+	for i := 0; i < 40; i++ {
+		head = &gava.ClassDetails{ClassName: "com.acme.Node", SerialVersionUID: 1, ClassDescFlags: 0x02,
+			FieldDescription: []*gava.ClassField{{TypeCode: 'L', Name: "next", Data: head}}}
+	}
+	var buf bytes.Buffer
+	assert.NoError(t, gava.NewEncoder(&buf).Encode(head))
+	data := buf.Bytes()
+
+	_, err := gava.DecoderOptions{MaxValueLength: 1 << 16}.Decode(data)
+	assert.True(t, errors.Is(err, gava.ErrMaxValueLength), "%v", err)
+	_, err = gava.DecoderOptions{MaxValueLength: 1 << 16}.ParseDocument(data)
+	assert.True(t, errors.Is(err, gava.ErrMaxValueLength), "%v", err)
+
+	//Values aren't formatted when the stream is only written back
+	out, err := gava.Redact(data, &gava.Redactor{Key: []byte("key")})
+	assert.NoError(t, err)
+	assert.Equal(t, data, out)
+	out, err = gava.Sanitize(data, &gava.ClassFilter{})
+	assert.NoError(t, err)
+	assert.Equal(t, data, out)
+}
+
+func TestDecoderArrayLength(t *testing.T) {
+	//A byte array claiming 0x7fffffff elements
+	data := pkg.DecodeHex("aced000575720002" + "5b42" + "acf317f8060854e0" + "020000" + "7870" + "7fffffff" + "01020304")
+
+	_, err := gava.NewGavaDeserilizer(data).Decode()
+	assert.True(t, errors.Is(err, io.ErrUnexpectedEOF), "%v", err)
+	_, err = gava.DecoderOptions{MaxArrayLength: 1000}.Decode(data)
+	assert.True(t, errors.Is(err, gava.ErrMaxArrayLength), "%v", err)
+
+	_, err = gava.NewGavaDeserilizer([]byte(readLine("./test.txt"))).Decode()
+	assert.NoError(t, err)
+	_, err = gava.DecoderOptions{MaxArrayLength: 3}.Decode([]byte(readLine("./test.txt")))
+	assert.True(t, errors.Is(err, gava.ErrMaxArrayLength), "%v", err)
+
+	var v struct{ B int32 }
+	err = gava.UnmarshalOptions{Decoder: gava.DecoderOptions{MaxArrayLength: 3}}.Unmarshal([]byte(readLine("./test.txt")), &v)
+	assert.True(t, errors.Is(err, gava.ErrMaxArrayLength), "%v", err)
+}
@@ -2,6 +2,9 @@ package test
 
 import (
 	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"testing"
@@ -47,6 +50,97 @@ func TestHex(t *testing.T) {
 	assert.Equal(t, len(parsedObject.FieldDescription), 4)
 }
 
+// proxyHex is a proxy implementing java.lang.Runnable, with a null invocation handler.
+const proxyHex = "aced0005737d0000000100126a6176612e6c616e672e52756e6e61626c6578720017" +
+	"6a6176612e6c616e672e7265666c6563742e50726f7879e127da20cc1043cb0200014c0001687400" +
+	"254c6a6176612f6c616e672f7265666c6563742f496e766f636174696f6e48616e646c65723b787070"
+
+func TestProxyClassDesc(t *testing.T) {
+	parsedObject, err := gava.NewGavaDeserilizer(pkg.DecodeHex(proxyHex)).Decode()
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"java.lang.Runnable"}, parsedObject.ProxyInterfaces)
+	assert.Equal(t, "", parsedObject.ClassName)
+	assert.Equal(t, "java.lang.reflect.Proxy", parsedObject.SuperClass.ClassName)
+	assert.Nil(t, parsedObject.Field("h").Data)
+}
+
+func TestNullClassDesc(t *testing.T) {
+	//An object and a class of a null descriptor in the field x of Test
+	for _, hexB := range []string{
+		"aced0005737200045465737400000000000000000200014c0001787400124c6a6176612f6c616e672f4f626a6563743b78707370",
+		"aced0005737200045465737400000000000000000200014c0001787400124c6a6176612f6c616e672f4f626a6563743b78707670",
+	} {
+		data := pkg.DecodeHex(hexB)
+		_, err := gava.NewGavaDeserilizer(data).Decode()
+		assert.EqualError(t, err, "cd is nil (offset 52)")
+		_, err = gava.ParseDocument(data)
+		assert.Error(t, err)
+		_, err = gava.NewIndex(data)
+		assert.Error(t, err)
+		var v struct{ X interface{} }
+		assert.Error(t, gava.Unmarshal(data, &v))
+		assert.NotEmpty(t, gava.Validate(data))
+		assert.NotPanics(t, func() { gava.Scan(data) })
+	}
+}
+
 func TestMain(m *testing.M) {
 	os.Exit(m.Run())
 }
+
+func TestInvalidArrayClass(t *testing.T) {
+	//Arrays of descriptors named "" and "[", and of a proxy descriptor
+	for _, hexB := range []string{
+		"aced000575720000000000000000000002000078700000000000",
+		"aced0005757200015b0000000000000000020000787000000000",
+		"aced000575" + proxyHex[10:] + "00000000",
+	} {
+		data := pkg.DecodeHex(hexB)
+		_, err := gava.NewGavaDeserilizer(data).Decode()
+		var parseErr *gava.ParseError
+		assert.True(t, errors.As(err, &parseErr), "%s: %v", hexB, err)
+		_, err = gava.ParseDocument(data)
+		assert.Error(t, err)
+		_, err = gava.NewIndex(data)
+		assert.Error(t, err)
+		assert.NotEmpty(t, gava.Validate(data))
+		assert.NotPanics(t, func() { gava.Scan(data) })
+	}
+}
+
+func TestObjectFieldValue(t *testing.T) {
+	node := func(next interface{}) *gava.ClassDetails {
+		return &gava.ClassDetails{ClassName: "com.acme.Node", SerialVersionUID: 1, ClassDescFlags: 0x02,
+			FieldDescription: []*gava.ClassField{{TypeCode: 'L', Name: "next", Data: next}}}
+	}
+	var buf bytes.Buffer
+	assert.NoError(t, gava.NewEncoder(&buf).Encode(node(node(nil))))
+
+	//Objects that aren't decoded are reported as JSON
+	parsedObject, err := gava.NewGavaDeserilizer(buf.Bytes()).Decode()
+	assert.NoError(t, err)
+	var next gava.ClassDetails
+	assert.NoError(t, json.Unmarshal([]byte(parsedObject.Field("next").Value), &next))
+	assert.Equal(t, "com.acme.Node", next.ClassName)
+	assert.Equal(t, "null", next.FieldDescription[0].Value)
+}
+
+func TestCyclicArray(t *testing.T) {
+	//Object[] a = new Object[1]; a[0] = a;
+	a := &gava.JavaArray{ClassName: "[Ljava.lang.Object;", Elements: []interface{}{nil}}
+	a.Elements[0] = a
+	holder := &gava.ClassDetails{ClassName: "com.acme.Holder", SerialVersionUID: 1, ClassDescFlags: 0x02,
+		FieldDescription: []*gava.ClassField{{TypeCode: '[', Name: "values", Data: a}}}
+	var buf bytes.Buffer
+	assert.NoError(t, gava.NewEncoder(&buf).Encode(holder))
+
+	parsedObject, err := gava.NewGavaDeserilizer(buf.Bytes()).Decode()
+	assert.NoError(t, err)
+	assert.Equal(t, "[[...]]", parsedObject.Field("values").Value)
+
+	var v struct{ Values interface{} }
+	assert.NoError(t, gava.Unmarshal(buf.Bytes(), &v))
+	values := v.Values.([]interface{})
+	assert.IsType(t, &gava.JavaArray{}, values[0])
+}
@@ -0,0 +1,82 @@
+package test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/maPaydar/gava-deserializer"
+	"github.com/maPaydar/gava-deserializer/pkg"
+	"github.com/stretchr/testify/assert"
+)
+
+type entity struct {
+	_  struct{} `javaclass:"com.acme.Entity" suid:"1"`
+	ID int64    `java:"id"`
+}
+
+type orderLine struct {
+	_        struct{} `javaclass:"com.acme.OrderLine" suid:"2"`
+	Sku      string
+	Quantity int
+}
+
+type order struct {
+	_ struct{} `javaclass:"com.acme.Order" suid:"123"`
+	entity
+	Customer *string
+	Lines    []*orderLine
+	Discount *int32
+	Express  bool
+	Attrs    map[string]interface{}
+	Payload  []byte
+	Primary  *orderLine
+}
+
+func TestMarshalArrayList(t *testing.T) {
+	data, err := gava.Marshal([]string{"a", "b"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, pkg.DecodeHex("aced0005737200136a6176612e7574696c2e41727261794c6973747881d21d99c7619d03000149000473697a65787000000002770400000002740001617400016278"), data)
+}
+
+func TestMarshal(t *testing.T) {
+	customer := "acme"
+	line := &orderLine{Sku: "X-1", Quantity: 2}
+	in := order{
+		entity:   entity{ID: 42},
+		Customer: &customer,
+		Lines:    []*orderLine{line, {Sku: "Y-2", Quantity: 1}},
+		Express:  true,
+		Attrs:    map[string]interface{}{"gift": true, "weight": 1.5},
+		Payload:  []byte{1, 2, 3},
+		Primary:  line,
+	}
+
+	data, err := gava.Marshal(&in)
+	assert.NoError(t, err)
+
+	parsedObject, err := gava.NewGavaDeserilizer(data).Decode()
+	assert.NoError(t, err)
+	assert.Equal(t, "com.acme.Order", parsedObject.ClassName)
+	assert.Equal(t, int64(123), parsedObject.SerialVersionUID)
+	assert.Equal(t, "com.acme.Entity", parsedObject.SuperClass.ClassName)
+	var names []string
+	for _, f := range parsedObject.FieldDescription {
+		names = append(names, f.Name)
+	}
+	assert.Equal(t, []string{"express", "attrs", "customer", "discount", "lines", "payload", "primary"}, names)
+	assert.Same(t, parsedObject.Field("primary").Data, parsedObject.Field("lines").Data.(*gava.ClassDetails).Annotation[1])
+
+	var out order
+	assert.NoError(t, gava.Unmarshal(data, &out))
+	assert.Equal(t, in, out)
+	assert.Same(t, out.Lines[0], out.Primary)
+
+	//Nil struct pointers are declared with the class of their type too
+	data, err = gava.Marshal(&order{})
+	assert.NoError(t, err)
+	assert.True(t, bytes.Contains(data, []byte("Lcom/acme/OrderLine;")))
+
+	_, err = gava.Marshal(struct{ X int }{})
+	assert.IsType(t, &gava.UnsupportedTypeError{}, err)
+}
@@ -0,0 +1,89 @@
+package test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/maPaydar/gava-deserializer"
+	"github.com/stretchr/testify/assert"
+)
+
+func byteArray(b []byte) *gava.JavaArray {
+	array := &gava.JavaArray{ClassName: "[B"}
+	for _, c := range b {
+		array.Elements = append(array.Elements, int8(c))
+	}
+	return array
+}
+
+func signedObject(t *testing.T, content []byte) []byte {
+	signed := &gava.ClassDetails{
+		ClassName:        "java.security.SignedObject",
+		SerialVersionUID: 720502720485447167,
+		ClassDescFlags:   0x02,
+		FieldDescription: []*gava.ClassField{
+			{TypeCode: '[', Name: "content", Data: byteArray(content)},
+			{TypeCode: '[', Name: "signature", Data: byteArray([]byte{1, 2, 3})},
+			{TypeCode: 'L', Name: "thealgorithm", Data: gava.String("SHA256withRSA")},
+		},
+	}
+	var buf bytes.Buffer
+	assert.NoError(t, gava.NewEncoder(&buf).Encode(signed))
+	return buf.Bytes()
+}
+
+func TestNestedStreams(t *testing.T) {
+	data := signedObject(t, signedObject(t, []byte(readLine("./test.txt"))))
+
+	doc, err := gava.ParseDocument(data)
+	assert.NoError(t, err)
+	assert.Empty(t, doc.Nested)
+
+	doc, err = gava.DecoderOptions{NestedDepth: 1}.ParseDocument(data)
+	assert.NoError(t, err)
+	assert.Len(t, doc.Nested, 1)
+	nested := doc.Nested[0]
+	assert.Same(t, doc.Object(), nested.Holder)
+	assert.Equal(t, "java.security.SignedObject", nested.Class)
+	assert.Equal(t, "content", nested.Field)
+	assert.Equal(t, 0, nested.Offset)
+	assert.NoError(t, nested.Err)
+	assert.Equal(t, "java.security.SignedObject", nested.Document.Object().ClassName)
+	assert.Empty(t, nested.Document.Nested)
+
+	doc, err = gava.DecoderOptions{NestedDepth: 2}.ParseDocument(data)
+	assert.NoError(t, err)
+	inner := doc.Nested[0].Document.Nested
+	assert.Len(t, inner, 1)
+	assert.Equal(t, "Test", inner[0].Document.Object().ClassName)
+	out, err := inner[0].Document.Bytes()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte(readLine("./test.txt")), out)
+}
+
+func TestNestedStreamInBlockData(t *testing.T) {
+	stream := []byte(readLine("./test.txt"))
+	marshalled := &gava.ClassDetails{
+		ClassName:        "com.acme.Envelope",
+		SerialVersionUID: 1,
+		ClassDescFlags:   0x03,
+		//writeObject wrote a length, then the stream in two blocks
+		Annotation: []interface{}{gava.BlockData(append([]byte{0, 0, 0, byte(len(stream))}, stream[:40]...)), gava.BlockData(stream[40:])},
+	}
+	var buf bytes.Buffer
+	e := gava.NewEncoder(&buf)
+	assert.NoError(t, e.Encode(marshalled))
+	//A truncated stream is reported with its error
+	assert.NoError(t, e.Encode(gava.BlockData(stream[:20])))
+
+	doc, err := gava.DecoderOptions{NestedDepth: 1}.ParseDocument(buf.Bytes())
+	assert.NoError(t, err)
+	assert.Len(t, doc.Nested, 2)
+	assert.Equal(t, "com.acme.Envelope", doc.Nested[0].Class)
+	assert.Equal(t, "", doc.Nested[0].Field)
+	assert.Equal(t, 4, doc.Nested[0].Offset)
+	assert.Equal(t, "Test", doc.Nested[0].Document.Object().ClassName)
+	assert.Nil(t, doc.Nested[1].Holder)
+	assert.Nil(t, doc.Nested[1].Document)
+	assert.Error(t, doc.Nested[1].Err)
+}
@@ -0,0 +1,68 @@
+package test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/maPaydar/gava-deserializer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedact(t *testing.T) {
+	data := []byte(readLine("./test.txt"))
+	r := &gava.Redactor{Key: []byte("secret"), Fields: []string{"Test.b"}}
+
+	out, err := gava.Redact(data, r)
+	assert.NoError(t, err)
+	parsedObject, err := gava.NewGavaDeserilizer(out).Decode()
+	assert.NoError(t, err)
+	original, err := gava.NewGavaDeserilizer(data).Decode()
+	assert.NoError(t, err)
+
+	assert.Equal(t, original.ClassName, parsedObject.ClassName)
+	assert.Equal(t, original.SerialVersionUID, parsedObject.SerialVersionUID)
+	a := parsedObject.Field("a").Data.(*gava.JavaString).Value
+	assert.Len(t, a, 16)
+	assert.NotEqual(t, "aa", a)
+	assert.NotEqual(t, int32(1), parsedObject.Field("b").Data)
+	assert.Equal(t, original.Field("c").Data.(*gava.JavaArray).Elements, parsedObject.Field("c").Data.(*gava.JavaArray).Elements)
+
+	//Pseudonyms are deterministic
+	again, err := gava.Redact(data, r)
+	assert.NoError(t, err)
+	assert.Equal(t, out, again)
+}
+
+func TestRedactKeepsStructure(t *testing.T) {
+	name := gava.String("alice")
+	customer := &gava.ClassDetails{
+		ClassName:        "com.acme.Customer",
+		SerialVersionUID: 1,
+		ClassDescFlags:   0x02,
+		FieldDescription: []*gava.ClassField{
+			{TypeCode: 'I', Name: "age", Data: int32(42)},
+			{TypeCode: 'J', Name: "balance", Data: int64(1000)},
+			{TypeCode: 'L', Name: "name", Data: name},
+			{TypeCode: 'L', Name: "nick", Data: gava.String("alice")},
+			{TypeCode: 'L', Name: "same", Data: name},
+			{TypeCode: 'L', Name: "status", Data: &gava.JavaEnum{ClassName: "com.acme.Status", Constant: "ACTIVE"}},
+		},
+	}
+	var buf bytes.Buffer
+	assert.NoError(t, gava.NewEncoder(&buf).Encode(customer))
+
+	doc, err := gava.ParseDocument(buf.Bytes())
+	assert.NoError(t, err)
+	assert.Equal(t, 3, doc.Redact(&gava.Redactor{Fields: []string{"com.acme.*.balance"}}))
+	out, err := doc.Bytes()
+	assert.NoError(t, err)
+	assert.NotContains(t, string(out), "alice")
+
+	parsedObject, err := gava.NewGavaDeserilizer(out).Decode()
+	assert.NoError(t, err)
+	assert.Equal(t, int32(42), parsedObject.Field("age").Data)
+	assert.NotEqual(t, int64(1000), parsedObject.Field("balance").Data)
+	assert.Equal(t, parsedObject.Field("name").Data.(*gava.JavaString).Value, parsedObject.Field("nick").Data.(*gava.JavaString).Value)
+	assert.Same(t, parsedObject.Field("name").Data, parsedObject.Field("same").Data)
+	assert.Equal(t, "ACTIVE", parsedObject.Field("status").Data.(*gava.JavaEnum).Constant)
+}
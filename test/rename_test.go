@@ -0,0 +1,66 @@
+package test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/maPaydar/gava-deserializer"
+	"github.com/maPaydar/gava-deserializer/pkg"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenamePackage(t *testing.T) {
+	hexB := "aced00057372002f696d2e6163746f722e7365727665722e6469616c6f672e47726f75704469616c6f675374617465536e617073686f74000000000000000002000449000767726f757049644c000f6c6173744d657373616765446174657400134c6a6176612f74696d652f496e7374616e743b4c000c6c617374526561644461746571007e00014c000f6c617374526563656976654461746571007e00017870000000007372000d6a6176612e74696d652e536572955d84ba1b2248b20c00007870770d02000000005b1bd9bb352ad700787371007e0003770d02000000005b056ab729f63000787371007e0003770d02000000005b1bd9bb352ad70078"
+	data := pkg.DecodeHex(hexB)
+
+	out, err := gava.RenameClasses(data, &gava.ClassRenamer{Packages: map[string]string{"im.actor.server": "im.actor.core"}})
+	assert.NoError(t, err)
+	assert.Equal(t, len(data)-2, len(out))
+
+	parsedObject, err := gava.NewGavaDeserilizer(out).Decode()
+	assert.NoError(t, err)
+	assert.Equal(t, "im.actor.core.dialog.GroupDialogStateSnapshot", parsedObject.ClassName)
+	assert.Equal(t, "java.time.Ser", parsedObject.Field("lastReadDate").Data.(*gava.ClassDetails).ClassName)
+	//Everything after the class name is unchanged
+	assert.Equal(t, data[55:], out[53:])
+}
+
+func TestRenameClass(t *testing.T) {
+	line := &gava.ClassDetails{ClassName: "com.acme.Order$Line", SerialVersionUID: 1, ClassDescFlags: 0x02}
+	order := &gava.ClassDetails{
+		ClassName:        "com.acme.Order",
+		SerialVersionUID: 1,
+		ClassDescFlags:   0x02,
+		FieldDescription: []*gava.ClassField{
+			{TypeCode: 'L', Name: "first", Data: line},
+			{TypeCode: '[', Name: "lines", Data: &gava.JavaArray{ClassName: "[Lcom.acme.Order$Line;", Elements: []interface{}{line}}},
+			{TypeCode: 'L', Name: "next", Data: nil},
+		},
+	}
+	var buf bytes.Buffer
+	assert.NoError(t, gava.NewEncoder(&buf).Encode(order))
+
+	doc, err := gava.ParseDocument(buf.Bytes())
+	assert.NoError(t, err)
+	assert.Equal(t, 3, doc.RenameClasses(&gava.ClassRenamer{Classes: map[string]string{"com.acme.Order": "com.shop.Order"}}))
+	out, err := doc.Bytes()
+	assert.NoError(t, err)
+
+	assert.NotContains(t, string(out), "acme")
+	assert.Contains(t, string(out), "Lcom/shop/Order$Line;")
+	assert.Contains(t, string(out), "[Lcom.shop.Order$Line;")
+	parsedObject, err := gava.NewGavaDeserilizer(out).Decode()
+	assert.NoError(t, err)
+	assert.Equal(t, "com.shop.Order$Line", parsedObject.Field("first").Data.(*gava.ClassDetails).ClassName)
+	assert.Same(t, parsedObject.Field("first").Data, parsedObject.Field("lines").Data.(*gava.JavaArray).Elements[0])
+}
+
+func TestRenameProxyInterfaces(t *testing.T) {
+	out, err := gava.RenameClasses(pkg.DecodeHex(proxyHex), &gava.ClassRenamer{Classes: map[string]string{"java.lang.Runnable": "com.acme.Task"}})
+	assert.NoError(t, err)
+
+	parsedObject, err := gava.NewGavaDeserilizer(out).Decode()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"com.acme.Task"}, parsedObject.ProxyInterfaces)
+	assert.Equal(t, "java.lang.reflect.Proxy", parsedObject.SuperClass.ClassName)
+}
@@ -0,0 +1,100 @@
+package test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/maPaydar/gava-deserializer"
+	"github.com/maPaydar/gava-deserializer/pkg"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSanitizeDeny(t *testing.T) {
+	hexB := "aced00057372002f696d2e6163746f722e7365727665722e6469616c6f672e47726f75704469616c6f675374617465536e617073686f74000000000000000002000449000767726f757049644c000f6c6173744d657373616765446174657400134c6a6176612f74696d652f496e7374616e743b4c000c6c617374526561644461746571007e00014c000f6c617374526563656976654461746571007e00017870000000007372000d6a6176612e74696d652e536572955d84ba1b2248b20c00007870770d02000000005b1bd9bb352ad700787371007e0003770d02000000005b056ab729f63000787371007e0003770d02000000005b1bd9bb352ad70078"
+	doc, err := gava.ParseDocument(pkg.DecodeHex(hexB))
+	assert.NoError(t, err)
+
+	assert.Equal(t, 3, doc.Sanitize(&gava.ClassFilter{Deny: []string{"java.time.*"}}))
+	out, err := doc.Bytes()
+	assert.NoError(t, err)
+	assert.NotContains(t, string(out), "java.time.Ser")
+
+	parsedObject, err := gava.NewGavaDeserilizer(out).Decode()
+	assert.NoError(t, err)
+	assert.Equal(t, "im.actor.server.dialog.GroupDialogStateSnapshot", parsedObject.ClassName)
+	assert.Nil(t, parsedObject.Field("lastMessageDate").Data)
+	assert.Nil(t, parsedObject.Field("lastReceiveDate").Data)
+}
+
+func TestSanitizeAllow(t *testing.T) {
+	shared := &gava.ClassDetails{ClassName: "com.acme.Line", SerialVersionUID: 1, ClassDescFlags: 0x02}
+	gadget := &gava.ClassDetails{ClassName: "org.evil.Gadget", SerialVersionUID: 1, ClassDescFlags: 0x02}
+	order := &gava.ClassDetails{
+		ClassName:        "com.acme.Order",
+		SerialVersionUID: 1,
+		ClassDescFlags:   0x02,
+		FieldDescription: []*gava.ClassField{
+			{TypeCode: 'L', Name: "extra", Data: gadget},
+			{TypeCode: 'L', Name: "first", Data: shared},
+			{TypeCode: '[', Name: "items", Data: &gava.JavaArray{ClassName: "[Ljava.lang.Object;", Elements: []interface{}{gadget, shared, gadget}}},
+		},
+	}
+	var buf bytes.Buffer
+	assert.NoError(t, gava.NewEncoder(&buf).Encode(order))
+
+	out, err := gava.Sanitize(buf.Bytes(), &gava.ClassFilter{Allow: []string{"com.acme.**", "java.lang.Object"}})
+	assert.NoError(t, err)
+	//Only the type string of the field remains, Java doesn't load field types
+	assert.NotContains(t, string(out), "org.evil.Gadget")
+
+	parsedObject, err := gava.NewGavaDeserilizer(out).Decode()
+	assert.NoError(t, err)
+	assert.Nil(t, parsedObject.Field("extra").Data)
+	items := parsedObject.Field("items").Data.(*gava.JavaArray).Elements
+	assert.Equal(t, []interface{}{nil, parsedObject.Field("first").Data, nil}, items)
+	assert.Same(t, parsedObject.Field("first").Data, items[1])
+}
+
+func TestClassFilter(t *testing.T) {
+	f := &gava.ClassFilter{Allow: []string{"java.util.*", "com.acme.**", "org.Foo*"}, Deny: []string{"com.acme.internal.Secret"}}
+	assert.True(t, f.Allowed("java.util.ArrayList"))
+	assert.False(t, f.Allowed("java.util.concurrent.ConcurrentHashMap"))
+	assert.True(t, f.Allowed("com.acme.shop.Order"))
+	assert.False(t, f.Allowed("com.acme.internal.Secret"))
+	assert.True(t, f.Allowed("org.FooBar"))
+	assert.True(t, f.Allowed("[[Lcom.acme.Order;"))
+	assert.False(t, f.Allowed("[Ljava.lang.Runtime;"))
+	assert.True(t, f.Allowed("[B"))
+}
+
+func TestSanitizeProxy(t *testing.T) {
+	doc, err := gava.ParseDocument(pkg.DecodeHex(proxyHex))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, doc.Sanitize(&gava.ClassFilter{Deny: []string{"java.util.*"}}))
+
+	//Proxies are judged by their interfaces
+	assert.Equal(t, 1, doc.Sanitize(&gava.ClassFilter{Deny: []string{"java.lang.Runnable"}}))
+	out, err := doc.Bytes()
+	assert.NoError(t, err)
+	assert.NotContains(t, string(out), "java.lang.Runnable")
+}
+
+func TestSanitizeAnnotations(t *testing.T) {
+	//Objects written by writeObject hold block data between their values
+	hexB := "aced00057372002f696d2e6163746f722e7365727665722e6469616c6f672e47726f75704469616c6f675374617465536e617073686f74000000000000000002000449000767726f757049644c000f6c6173744d657373616765446174657400134c6a6176612f74696d652f496e7374616e743b4c000c6c617374526561644461746571007e00014c000f6c617374526563656976654461746571007e00017870000000007372000d6a6176612e74696d652e536572955d84ba1b2248b20c00007870770d02000000005b1bd9bb352ad700787371007e0003770d02000000005b056ab729f63000787371007e0003770d02000000005b1bd9bb352ad70078"
+	out, err := gava.Sanitize(pkg.DecodeHex(hexB), &gava.ClassFilter{})
+	assert.NoError(t, err)
+	assert.Equal(t, pkg.DecodeHex(hexB), out)
+
+	gadget := &gava.ClassDetails{ClassName: "org.evil.Gadget", SerialVersionUID: 1, ClassDescFlags: 0x02}
+	data, err := gava.Marshal([]interface{}{"a", gadget})
+	assert.NoError(t, err)
+	out, err = gava.Sanitize(data, &gava.ClassFilter{Deny: []string{"org.evil.**"}})
+	assert.NoError(t, err)
+	assert.NotContains(t, string(out), "org.evil.Gadget")
+
+	parsedObject, err := gava.NewGavaDeserilizer(out).Decode()
+	assert.NoError(t, err)
+	assert.Equal(t, "java.util.ArrayList", parsedObject.ClassName)
+	assert.Equal(t, []interface{}{"a", nil}, parsedObject.Decoded)
+}
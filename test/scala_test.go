@@ -0,0 +1,52 @@
+package test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/maPaydar/gava-deserializer"
+	"github.com/maPaydar/gava-deserializer/pkg"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScalaCollections(t *testing.T) {
+	hexB := "aced000573720023696d2e6163746f722e7365727665722e67726f75702e47726f7570536e617073686f7400000000000000010200034c00076d656d626572737400214c7363616c612f636f6c6c656374696f6e2f696d6d757461626c652f4c6973743b4c000873657474696e67737400204c7363616c612f636f6c6c656374696f6e2f696d6d757461626c652f4d61703b4c00057469746c6574000e4c7363616c612f4f7074696f6e3b7870737200327363616c612e636f6c6c656374696f6e2e696d6d757461626c652e4c6973742453657269616c697a6174696f6e50726f787900000000000000010300007870740005616c696365740003626f627372002c7363616c612e636f6c6c656374696f6e2e696d6d757461626c652e4c69737453657269616c697a65456e64248a5c635bf7530b6d020000787078737200327363616c612e636f6c6c656374696f6e2e67656e657269632e44656661756c7453657269616c697a6174696f6e50726f787900000000000000010300014c0007666163746f727974001a4c7363616c612f636f6c6c656374696f6e2f466163746f72793b7870737200257363616c612e636f6c6c656374696f6e2e4d6170466163746f727924546f466163746f727900000000000000010200014c0007666163746f727974001d4c7363616c612f636f6c6c656374696f6e2f4d6170466163746f72793b78707372001f7363616c612e636f6c6c656374696f6e2e696d6d757461626c652e4d617024000000000000000102000078707704000000017372000c7363616c612e5475706c653200000000000000010200024c00025f317400124c6a6176612f6c616e672f4f626a6563743b4c00025f327400124c6a6176612f6c616e672f4f626a6563743b78707400056d75746564737200116a6176612e6c616e672e426f6f6c65616ecd207280d59cfaee0200015a000576616c7565787001787372000a7363616c612e536f6d6500000000000000010200014c000576616c75657400124c6a6176612f6c616e672f4f626a6563743b7870740007467269656e6473"
+
+	parsedObject := gava.NewGavaDeserilizer(pkg.DecodeHex(hexB)).Parse()
+
+	assert.NotNil(t, parsedObject)
+	assert.Equal(t, []interface{}{"alice", "bob"}, parsedObject.Field("members").Data.(*gava.ClassDetails).Decoded)
+	assert.Equal(t, map[interface{}]interface{}{"muted": true}, parsedObject.Field("settings").Data.(*gava.ClassDetails).Decoded)
+	assert.Equal(t, gava.Option{Value: "Friends", Defined: true}, parsedObject.Field("title").Data.(*gava.ClassDetails).Decoded)
+}
+
+func TestScalaOptionMapKeys(t *testing.T) {
+	some := func(v interface{}) *gava.ClassDetails {
+		return &gava.ClassDetails{ClassName: "scala.Some", SerialVersionUID: 1, ClassDescFlags: 0x02,
+			FieldDescription: []*gava.ClassField{{TypeCode: 'L', Name: "value", Data: v}}}
+	}
+	hashMap := func(entries ...interface{}) *gava.ClassDetails {
+		return &gava.ClassDetails{
+			ClassName:        "java.util.HashMap",
+			SerialVersionUID: 362498820763181265,
+			ClassDescFlags:   0x03,
+			FieldDescription: []*gava.ClassField{
+				{TypeCode: 'F', Name: "loadFactor", Data: float32(0.75)},
+				{TypeCode: 'I', Name: "threshold", Data: int32(12)},
+			},
+			Annotation: append([]interface{}{gava.BlockData{0, 0, 0, 16, 0, 0, 0, byte(len(entries) / 2)}}, entries...),
+		}
+	}
+	emptyList := &gava.ClassDetails{ClassName: "java.util.Collections$EmptyList", SerialVersionUID: 1, ClassDescFlags: 0x02}
+
+	var buf bytes.Buffer
+	e := gava.NewEncoder(&buf)
+	assert.NoError(t, e.Encode(hashMap(some(gava.String("x")), gava.String("y"))))
+	//An Option holding a list can't be a Go map key, the map is left undecoded
+	assert.NoError(t, e.Encode(hashMap(some(emptyList), gava.String("y"))))
+	doc, err := gava.ParseDocument(buf.Bytes())
+	assert.NoError(t, err)
+
+	assert.Equal(t, map[interface{}]interface{}{gava.Option{Value: "x", Defined: true}: "y"}, doc.Contents[0].(*gava.ClassDetails).Decoded)
+	assert.Nil(t, doc.Contents[1].(*gava.ClassDetails).Decoded)
+}
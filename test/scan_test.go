@@ -0,0 +1,142 @@
+package test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/maPaydar/gava-deserializer"
+	"github.com/stretchr/testify/assert"
+)
+
+func transformer(class string, fields ...*gava.ClassField) *gava.ClassDetails {
+	return &gava.ClassDetails{ClassName: class, SerialVersionUID: 1, ClassDescFlags: 0x02, FieldDescription: fields}
+}
+
+// commonsCollections1 builds the shape of the CommonsCollections1 payload: an annotation
+// handler holding a Map proxy whose handler holds a LazyMap of transformers.
+func commonsCollections1(t *testing.T) []byte {
+	chain := transformer("org.apache.commons.collections.functors.ChainedTransformer",
+		&gava.ClassField{TypeCode: '[', Name: "iTransformers", Data: &gava.JavaArray{
+			ClassName: "[Lorg.apache.commons.collections.Transformer;",
+			Elements: []interface{}{
+				transformer("org.apache.commons.collections.functors.ConstantTransformer"),
+				transformer("org.apache.commons.collections.functors.InvokerTransformer",
+					&gava.ClassField{TypeCode: 'L', Name: "iMethodName", Data: gava.String("exec")}),
+			},
+		}})
+	lazyMap := transformer("org.apache.commons.collections.map.LazyMap", &gava.ClassField{TypeCode: 'L', Name: "factory", Data: chain})
+	handler := func(memberValues interface{}) *gava.ClassDetails {
+		return transformer("sun.reflect.annotation.AnnotationInvocationHandler", &gava.ClassField{TypeCode: 'L', Name: "memberValues", Data: memberValues})
+	}
+	proxy := &gava.ClassDetails{
+		ProxyInterfaces: []string{"java.util.Map"},
+		ClassDescFlags:  0x02,
+		SuperClass:      transformer("java.lang.reflect.Proxy", &gava.ClassField{TypeCode: 'L', Name: "h", Data: handler(lazyMap)}),
+	}
+	var buf bytes.Buffer
+	assert.NoError(t, gava.NewEncoder(&buf).Encode(handler(proxy)))
+	return buf.Bytes()
+}
+
+func TestScan(t *testing.T) {
+	data := commonsCollections1(t)
+
+	//Proxy class descriptors are read and written back
+	doc, err := gava.ParseDocument(data)
+	assert.NoError(t, err)
+	proxy := doc.Object().Field("memberValues").Data.(*gava.ClassDetails)
+	assert.Equal(t, []string{"java.util.Map"}, proxy.ProxyInterfaces)
+	out, err := doc.Bytes()
+	assert.NoError(t, err)
+	assert.Equal(t, data, out)
+
+	findings := gava.Scan(data)
+	var rules []string
+	for _, f := range findings {
+		rules = append(rules, f.Rule)
+	}
+	assert.Equal(t, []string{
+		"annotation-invocation-handler",
+		"commons-collections-chained-transformer",
+		"commons-collections-lazy-map",
+		"commons-collections-invoker-transformer",
+	}, rules)
+
+	invoker := findings[3]
+	assert.Equal(t, gava.SeverityCritical, invoker.Severity)
+	assert.Equal(t, []string{
+		"sun.reflect.annotation.AnnotationInvocationHandler",
+		"$Proxy(java.util.Map)",
+		"sun.reflect.annotation.AnnotationInvocationHandler",
+		"org.apache.commons.collections.map.LazyMap",
+		"org.apache.commons.collections.functors.ChainedTransformer",
+		"[Lorg.apache.commons.collections.Transformer;",
+		"org.apache.commons.collections.functors.InvokerTransformer",
+	}, invoker.Path)
+	assert.Equal(t, 4, invoker.Offsets[0])
+	for i, offset := range invoker.Offsets {
+		assert.Contains(t, []byte{0x73, 0x75}, data[offset], invoker.Path[i]) //TC_OBJECT or TC_ARRAY
+		if i > 0 {
+			assert.True(t, offset > invoker.Offsets[i-1])
+		}
+	}
+}
+
+func TestScanChain(t *testing.T) {
+	queue := transformer("java.util.PriorityQueue",
+		&gava.ClassField{TypeCode: 'I', Name: "size", Data: int32(2)},
+		&gava.ClassField{TypeCode: 'L', Name: "comparator", Data: transformer("org.apache.commons.collections4.comparators.TransformingComparator",
+			&gava.ClassField{TypeCode: 'L', Name: "transformer", Data: transformer("org.apache.commons.collections4.functors.InvokerTransformer")})})
+	var buf bytes.Buffer
+	assert.NoError(t, gava.NewEncoder(&buf).Encode(queue))
+
+	findings := gava.Scan(buf.Bytes())
+	assert.Len(t, findings, 2)
+	assert.Equal(t, "priority-queue-comparator", findings[0].Rule)
+	assert.Equal(t, []string{"java.util.PriorityQueue", "org.apache.commons.collections4.comparators.TransformingComparator"}, findings[0].Path)
+	assert.Equal(t, "commons-collections-invoker-transformer", findings[1].Rule)
+
+	//The comparator alone isn't the chain
+	buf.Reset()
+	assert.NoError(t, gava.NewEncoder(&buf).Encode(queue.Field("comparator").Data))
+	findings = gava.Scan(buf.Bytes())
+	assert.Len(t, findings, 1)
+	assert.Equal(t, "commons-collections-invoker-transformer", findings[0].Rule)
+
+	assert.Empty(t, gava.Scan([]byte(readLine("./test.txt"))))
+}
+
+func TestScanTruncated(t *testing.T) {
+	data := commonsCollections1(t)
+	data = data[:bytes.Index(data, []byte("iMethodName"))]
+
+	var rules []string
+	for _, f := range gava.Scan(data) {
+		rules = append(rules, f.Rule)
+	}
+	assert.Equal(t, []string{
+		"commons-collections-invoker-transformer",
+		"commons-collections-chained-transformer",
+		"commons-collections-lazy-map",
+		"annotation-invocation-handler",
+	}, rules)
+}
+
+func TestLoadGadgetRules(t *testing.T) {
+	rules, err := gava.LoadGadgetRules(strings.NewReader(`[{"id": "acme-runner", "severity": "high", "chain": [["com.acme.Runner"]]}]`))
+	assert.NoError(t, err)
+	assert.Equal(t, []gava.GadgetRule{{ID: "acme-runner", Severity: gava.SeverityHigh, Chain: [][]string{{"com.acme.Runner"}}}}, rules)
+
+	scanner := &gava.Scanner{Rules: append(gava.DefaultGadgetRules(), rules...)}
+	var buf bytes.Buffer
+	assert.NoError(t, gava.NewEncoder(&buf).Encode(transformer("com.acme.Runner")))
+	findings := scanner.Scan(buf.Bytes())
+	assert.Len(t, findings, 1)
+	assert.Equal(t, "acme-runner", findings[0].Rule)
+
+	_, err = gava.LoadGadgetRules(strings.NewReader(`[{"id": "x", "severity": "severe", "chain": [["a"]]}]`))
+	assert.Error(t, err)
+	_, err = gava.LoadGadgetRules(strings.NewReader(`[{"id": "x", "severity": "low", "chain": []}]`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,68 @@
+package test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/maPaydar/gava-deserializer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpans(t *testing.T) {
+	data := []byte(readLine("./test.txt"))
+	doc, err := gava.ParseDocument(data)
+	assert.NoError(t, err)
+
+	obj := doc.Object()
+	assert.Equal(t, []gava.Span{{Offset: 4, Length: len(data) - 4}}, doc.ContentSpans)
+	assert.Equal(t, doc.ContentSpans[0], obj.Span)
+	assert.Equal(t, len(data), obj.Span.End())
+
+	b, a, c := obj.Field("b"), obj.Field("a"), obj.Field("c")
+	assert.Equal(t, gava.Span{Offset: 63, Length: 4}, b.Span)
+	assert.Equal(t, []byte{0, 0, 0, 1}, data[b.Span.Offset:b.Span.End()])
+	assert.Equal(t, b.Span.End(), a.Span.Offset)
+	assert.Equal(t, a.Span, a.Data.(*gava.JavaString).Span)
+	assert.Equal(t, []byte{0x74, 0, 2, 'a', 'a'}, data[a.Span.Offset:a.Span.End()])
+	array := c.Data.(*gava.JavaArray)
+	assert.Equal(t, c.Span, array.Span)
+	assert.Equal(t, byte(0x75), data[array.Span.Offset])
+	assert.Equal(t, len(data), array.Span.End())
+}
+
+func TestAnnotationSpans(t *testing.T) {
+	inner := annotated("com.acme.Inner", 0x02)
+	outer := &gava.ClassDetails{
+		ClassName:        "com.acme.Outer",
+		SerialVersionUID: 1,
+		ClassDescFlags:   0x02,
+		FieldDescription: []*gava.ClassField{{TypeCode: 'L', Name: "inner", Data: inner}},
+		SuperClass:       annotated("com.acme.Base", 0x03, gava.BlockData{1, 2, 3}, gava.String("note"), inner),
+	}
+	var buf bytes.Buffer
+	e := gava.NewEncoder(&buf)
+	assert.NoError(t, e.Encode(outer))
+	assert.NoError(t, e.Encode(gava.BlockData{9}))
+	data := buf.Bytes()
+
+	doc, err := gava.ParseDocument(data)
+	assert.NoError(t, err)
+	assert.Len(t, doc.ContentSpans, 2)
+	assert.Equal(t, []byte{0x77, 1, 9}, data[doc.ContentSpans[1].Offset:doc.ContentSpans[1].End()])
+
+	obj := doc.Object()
+	base := obj.SuperClass
+	//The class data of the super class comes first, with the inner object in its annotations
+	assert.Equal(t, []byte{0, 0, 0, 7, 0x77, 3, 1, 2, 3}, data[base.Span.Offset:base.Span.Offset+9])
+	assert.Equal(t, byte(0x78), data[base.Span.End()-1])
+	assert.Equal(t, obj.Field("inner").Span, gava.Span{Offset: base.Span.End(), Length: 5}) //a reference
+	assert.Equal(t, obj.Span.End(), obj.Field("inner").Span.End())
+
+	assert.Len(t, base.AnnotationSpans, 3)
+	block := base.AnnotationSpans[0]
+	assert.Equal(t, []byte{0x77, 3, 1, 2, 3}, data[block.Offset:block.End()])
+	assert.Equal(t, block.End(), base.AnnotationSpans[1].Offset)
+	assert.Equal(t, base.AnnotationSpans[1], base.Annotation[1].(*gava.JavaString).Span)
+	assert.Equal(t, base.AnnotationSpans[2], base.Annotation[2].(*gava.ClassDetails).Span)
+	assert.Equal(t, base.Span.End()-1, base.AnnotationSpans[2].End())
+}
@@ -0,0 +1,41 @@
+package test
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/maPaydar/gava-deserializer"
+	"github.com/maPaydar/gava-deserializer/pkg"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassFileSUID(t *testing.T) {
+	data, err := ioutil.ReadFile("./Test.class")
+	assert.NoError(t, err)
+
+	//test.txt was written by Java from Test.class
+	className, suid, err := gava.ClassFileSUID(data)
+	assert.NoError(t, err)
+	assert.Equal(t, "Test", className)
+	parsedObject, err := gava.NewGavaDeserilizer([]byte(readLine("./test.txt"))).Decode()
+	assert.NoError(t, err)
+	assert.Equal(t, parsedObject.SerialVersionUID, suid)
+
+	_, _, err = gava.ClassFileSUID(data[:100])
+	assert.Error(t, err)
+}
+
+func TestRewriteSUIDs(t *testing.T) {
+	hexB := "aced00057372002f696d2e6163746f722e7365727665722e6469616c6f672e47726f75704469616c6f675374617465536e617073686f74000000000000000002000449000767726f757049644c000f6c6173744d657373616765446174657400134c6a6176612f74696d652f496e7374616e743b4c000c6c617374526561644461746571007e00014c000f6c617374526563656976654461746571007e00017870000000007372000d6a6176612e74696d652e536572955d84ba1b2248b20c00007870770d02000000005b1bd9bb352ad700787371007e0003770d02000000005b056ab729f63000787371007e0003770d02000000005b1bd9bb352ad70078"
+	data := pkg.DecodeHex(hexB)
+
+	out, changes, err := gava.RewriteSUIDs(data, map[string]int64{"java.time.Ser": 42, "com.acme.Missing": 1})
+	assert.NoError(t, err)
+	assert.Equal(t, []gava.SUIDChange{{ClassName: "java.time.Ser", Old: -7683839454370182990, New: 42}}, changes)
+	assert.Equal(t, len(data), len(out))
+
+	parsedObject, err := gava.NewGavaDeserilizer(out).Decode()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(42), parsedObject.Field("lastReadDate").Data.(*gava.ClassDetails).SerialVersionUID)
+	assert.Equal(t, int64(42), parsedObject.Field("lastReceiveDate").Data.(*gava.ClassDetails).SerialVersionUID)
+}
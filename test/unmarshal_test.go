@@ -0,0 +1,115 @@
+package test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/maPaydar/gava-deserializer"
+	"github.com/maPaydar/gava-deserializer/pkg"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnmarshal(t *testing.T) {
+	hexB := "aced000573720023696d2e6163746f722e7365727665722e67726f75702e47726f7570536e617073686f7400000000000000010200034c00076d656d626572737400214c7363616c612f636f6c6c656374696f6e2f696d6d757461626c652f4c6973743b4c000873657474696e67737400204c7363616c612f636f6c6c656374696f6e2f696d6d757461626c652f4d61703b4c00057469746c6574000e4c7363616c612f4f7074696f6e3b7870737200327363616c612e636f6c6c656374696f6e2e696d6d757461626c652e4c6973742453657269616c697a6174696f6e50726f787900000000000000010300007870740005616c696365740003626f627372002c7363616c612e636f6c6c656374696f6e2e696d6d757461626c652e4c69737453657269616c697a65456e64248a5c635bf7530b6d020000787078737200327363616c612e636f6c6c656374696f6e2e67656e657269632e44656661756c7453657269616c697a6174696f6e50726f787900000000000000010300014c0007666163746f727974001a4c7363616c612f636f6c6c656374696f6e2f466163746f72793b7870737200257363616c612e636f6c6c656374696f6e2e4d6170466163746f727924546f466163746f727900000000000000010200014c0007666163746f727974001d4c7363616c612f636f6c6c656374696f6e2f4d6170466163746f72793b78707372001f7363616c612e636f6c6c656374696f6e2e696d6d757461626c652e4d617024000000000000000102000078707704000000017372000c7363616c612e5475706c653200000000000000010200024c00025f317400124c6a6176612f6c616e672f4f626a6563743b4c00025f327400124c6a6176612f6c616e672f4f626a6563743b78707400056d75746564737200116a6176612e6c616e672e426f6f6c65616ecd207280d59cfaee0200015a000576616c7565787001787372000a7363616c612e536f6d6500000000000000010200014c000576616c75657400124c6a6176612f6c616e672f4f626a6563743b7870740007467269656e6473"
+
+	var snapshot struct {
+		Members  []string
+		Settings map[string]bool `java:"settings"`
+		Name     *string         `java:"title"`
+	}
+	err := gava.Unmarshal(pkg.DecodeHex(hexB), &snapshot)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"alice", "bob"}, snapshot.Members)
+	assert.Equal(t, map[string]bool{"muted": true}, snapshot.Settings)
+	if assert.NotNil(t, snapshot.Name) {
+		assert.Equal(t, "Friends", *snapshot.Name)
+	}
+
+	var mismatch struct {
+		Members int
+	}
+	err = gava.Unmarshal(pkg.DecodeHex(hexB), &mismatch)
+	assert.IsType(t, &gava.UnmarshalTypeError{}, err)
+	assert.NoError(t, gava.UnmarshalOptions{IgnoreTypeMismatch: true}.Unmarshal(pkg.DecodeHex(hexB), &mismatch))
+
+	var partial struct {
+		Members []string
+	}
+	err = gava.UnmarshalOptions{DisallowUnknownFields: true}.Unmarshal(pkg.DecodeHex(hexB), &partial)
+	assert.IsType(t, &gava.UnknownFieldError{}, err)
+}
+
+type memberList []string
+
+func (m *memberList) UnmarshalJavaStream(in *gava.ObjectInput) error {
+	for {
+		v, err := in.ReadObject()
+		if errors.Is(err, gava.ErrEndOfData) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if s, ok := v.(*gava.JavaString); ok {
+			*m = append(*m, s.Value)
+		}
+	}
+}
+
+type title string
+
+func (t *title) UnmarshalJava(obj *gava.ClassDetails) error {
+	*t = title(obj.ClassName + ":" + obj.Field("value").Value)
+	return nil
+}
+
+func TestUnmarshalHooks(t *testing.T) {
+	hexB := "aced000573720023696d2e6163746f722e7365727665722e67726f75702e47726f7570536e617073686f7400000000000000010200034c00076d656d626572737400214c7363616c612f636f6c6c656374696f6e2f696d6d757461626c652f4c6973743b4c000873657474696e67737400204c7363616c612f636f6c6c656374696f6e2f696d6d757461626c652f4d61703b4c00057469746c6574000e4c7363616c612f4f7074696f6e3b7870737200327363616c612e636f6c6c656374696f6e2e696d6d757461626c652e4c6973742453657269616c697a6174696f6e50726f787900000000000000010300007870740005616c696365740003626f627372002c7363616c612e636f6c6c656374696f6e2e696d6d757461626c652e4c69737453657269616c697a65456e64248a5c635bf7530b6d020000787078737200327363616c612e636f6c6c656374696f6e2e67656e657269632e44656661756c7453657269616c697a6174696f6e50726f787900000000000000010300014c0007666163746f727974001a4c7363616c612f636f6c6c656374696f6e2f466163746f72793b7870737200257363616c612e636f6c6c656374696f6e2e4d6170466163746f727924546f466163746f727900000000000000010200014c0007666163746f727974001d4c7363616c612f636f6c6c656374696f6e2f4d6170466163746f72793b78707372001f7363616c612e636f6c6c656374696f6e2e696d6d757461626c652e4d617024000000000000000102000078707704000000017372000c7363616c612e5475706c653200000000000000010200024c00025f317400124c6a6176612f6c616e672f4f626a6563743b4c00025f327400124c6a6176612f6c616e672f4f626a6563743b78707400056d75746564737200116a6176612e6c616e672e426f6f6c65616ecd207280d59cfaee0200015a000576616c7565787001787372000a7363616c612e536f6d6500000000000000010200014c000576616c75657400124c6a6176612f6c616e672f4f626a6563743b7870740007467269656e6473"
+
+	var snapshot struct {
+		Members memberList
+		Title   title
+	}
+	err := gava.Unmarshal(pkg.DecodeHex(hexB), &snapshot)
+
+	assert.NoError(t, err)
+	assert.Equal(t, memberList{"alice", "bob"}, snapshot.Members)
+	assert.Equal(t, title("scala.Some:Friends"), snapshot.Title)
+}
+
+func TestUnmarshalInterface(t *testing.T) {
+	obj := &gava.ClassDetails{ClassName: "com.acme.Entry", SerialVersionUID: 1, ClassDescFlags: 0x02,
+		FieldDescription: []*gava.ClassField{
+			{TypeCode: 'L', Name: "key", Data: gava.String("region")},
+			{TypeCode: 'I', Name: "count", Data: int32(3)},
+			{TypeCode: 'L', Name: "next", Data: nil},
+		}}
+	var buf bytes.Buffer
+	assert.NoError(t, gava.NewEncoder(&buf).Encode(obj))
+
+	//Fields and map values of empty interfaces get the same decoded values
+	var entry struct {
+		Key   interface{}
+		Count interface{}
+		Next  interface{}
+	}
+	assert.NoError(t, gava.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "region", entry.Key)
+	assert.Equal(t, int32(3), entry.Count)
+	assert.Nil(t, entry.Next)
+
+	hashMap := &gava.ClassDetails{ClassName: "java.util.HashMap", SerialVersionUID: 362498820763181265, ClassDescFlags: 0x03,
+		FieldDescription: []*gava.ClassField{
+			{TypeCode: 'F', Name: "loadFactor", Data: float32(0.75)},
+			{TypeCode: 'I', Name: "threshold", Data: int32(12)},
+		},
+		Annotation: []interface{}{gava.BlockData{0, 0, 0, 16, 0, 0, 0, 1}, gava.String("key"), gava.String("region")},
+	}
+	buf.Reset()
+	assert.NoError(t, gava.NewEncoder(&buf).Encode(hashMap))
+	var values map[string]interface{}
+	assert.NoError(t, gava.Unmarshal(buf.Bytes(), &values))
+	assert.Equal(t, map[string]interface{}{"key": "region"}, values)
+}
@@ -0,0 +1,91 @@
+package test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/maPaydar/gava-deserializer"
+	"github.com/stretchr/testify/assert"
+)
+
+func violations(data []byte) []string {
+	var messages []string
+	for _, v := range gava.Validate(data) {
+		messages = append(messages, v.Message)
+	}
+	return messages
+}
+
+func TestValidate(t *testing.T) {
+	assert.Empty(t, gava.Validate([]byte(readLine("./test.txt"))))
+	assert.Empty(t, gava.Validate(commonsCollections1(t)))
+
+	order := &gava.ClassDetails{
+		ClassName:        "com.acme.Order",
+		SerialVersionUID: 1,
+		ClassDescFlags:   0x02,
+		FieldDescription: []*gava.ClassField{
+			{TypeCode: 'L', Name: "name", Data: gava.String("x")},
+			{TypeCode: 'I', Name: "id", Data: int32(1)},
+			{TypeCode: '[', Name: "items", Data: &gava.JavaArray{ClassName: "[Ljava.lang.String;", Elements: []interface{}{gava.String("a"), transformer("com.acme.Item")}}},
+		},
+	}
+	var buf bytes.Buffer
+	assert.NoError(t, gava.NewEncoder(&buf).Encode(order))
+	assert.Equal(t, []string{
+		"field com.acme.Order.id is out of order, primitive fields come first and each kind is sorted by name",
+		"field com.acme.Order.items is out of order, primitive fields come first and each kind is sorted by name",
+		"element 1 of [Ljava.lang.String; is an object com.acme.Item",
+	}, violations(buf.Bytes()))
+	v := gava.Validate(buf.Bytes())
+	assert.Equal(t, 5, v[0].Offset) //the class descriptor
+	assert.True(t, v[2].Offset > v[1].Offset)
+	assert.True(t, strings.HasPrefix(v[2].String(), "offset "))
+}
+
+func TestValidateFields(t *testing.T) {
+	label := gava.String("label")
+	holder := &gava.ClassDetails{
+		ClassName:        "com.acme.Holder",
+		SerialVersionUID: 1,
+		ClassDescFlags:   0x02,
+		FieldDescription: []*gava.ClassField{
+			{TypeCode: 'L', Name: "a", Data: label},
+			{TypeCode: 'L', Name: "b", Data: label},
+		},
+	}
+	var buf bytes.Buffer
+	assert.NoError(t, gava.NewEncoder(&buf).Encode(holder))
+	assert.Empty(t, gava.Validate(buf.Bytes()))
+
+	//An array field referring to the string
+	holder.FieldDescription[1].TypeCode = '['
+	buf.Reset()
+	assert.NoError(t, gava.NewEncoder(&buf).Encode(holder))
+	assert.Equal(t, []string{
+		"array field com.acme.Holder.b holds a string",
+		`invalid type "Ljava/lang/String;" of array field com.acme.Holder.b`,
+	}, violations(buf.Bytes()))
+}
+
+func TestValidateBlockData(t *testing.T) {
+	var buf bytes.Buffer
+	e := gava.NewEncoder(&buf)
+	assert.NoError(t, e.Encode(annotated("com.acme.Block", 0x03, gava.BlockData(make([]byte, 2000)))))
+	assert.NoError(t, e.Encode(gava.BlockData{1, 2}))
+	data := buf.Bytes()
+	//The encoder writes 2000 bytes in one block, and the short block as TC_BLOCKDATALONG
+	short := len(data) - 4
+	data = append(data[:short], 0x7a, 0x00, 0x00, 0x00, 0x02, 0x01, 0x02)
+	assert.Equal(t, []string{
+		"block data of 2000 bytes in com.acme.Block, ObjectOutputStream writes blocks of at most 1024 bytes",
+		"TC_BLOCKDATALONG of 2 bytes, TC_BLOCKDATA holds up to 255",
+	}, violations(data))
+
+	//Damaged streams are reported with what can be checked
+	truncated := violations(data[:len(data)-1])
+	assert.Len(t, truncated, 3)
+	assert.Equal(t, "unexpected EOF", truncated[2])
+	assert.Equal(t, []string{"gava: invalid STREAM_MAGIC, should be 0xac ed"}, violations(data[2:]))
+}
@@ -0,0 +1,136 @@
+package test
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/maPaydar/gava-deserializer"
+	"github.com/stretchr/testify/assert"
+)
+
+// recorder records the events of a stream, skipping the fields and arrays named in skip.
+type recorder struct {
+	gava.BaseVisitor
+	events []string
+	skip   map[string]bool
+	stop   string
+}
+
+func (r *recorder) StartObject(obj *gava.ClassDetails) gava.Action {
+	r.events = append(r.events, fmt.Sprintf("object %s %x", obj.ClassName, obj.RefHandle))
+	if obj.ClassName == r.stop {
+		return gava.Stop
+	}
+	return gava.Continue
+}
+
+func (r *recorder) EndObject() { r.events = append(r.events, "end") }
+
+func (r *recorder) Field(part *gava.ClassDetails, field *gava.ClassField) gava.Action {
+	r.events = append(r.events, "field "+part.ClassName+"."+field.Name)
+	if r.skip[field.Name] {
+		return gava.Skip
+	}
+	return gava.Continue
+}
+
+func (r *recorder) StartArray(className string, length, handle int) gava.Action {
+	r.events = append(r.events, fmt.Sprintf("array %s %d", className, length))
+	if r.skip[className] {
+		return gava.Skip
+	}
+	return gava.Continue
+}
+
+func (r *recorder) EndArray() { r.events = append(r.events, "end") }
+
+func (r *recorder) Value(v interface{}) {
+	if s, ok := v.(*gava.JavaString); ok {
+		v = s.Value
+	}
+	r.events = append(r.events, fmt.Sprintf("value %v", v))
+}
+
+func (r *recorder) BlockData(data gava.BlockData) {
+	r.events = append(r.events, fmt.Sprintf("block % x", []byte(data)))
+}
+
+func (r *recorder) Reference(handle int) {
+	r.events = append(r.events, fmt.Sprintf("reference %x", handle))
+}
+
+func (r *recorder) Reset() { r.events = append(r.events, "reset") }
+
+func TestVisit(t *testing.T) {
+	r := &recorder{}
+	assert.NoError(t, gava.Visit([]byte(readLine("./test.txt")), r))
+	assert.Equal(t, []string{
+		"object Test 7e0003",
+		"field Test.b", "value 1",
+		"field Test.a", "value aa",
+		"field Test.c", "array [B 4", "value 1", "value 2", "value 3", "value 4", "end",
+		"end",
+	}, r.events)
+}
+
+func TestVisitSkip(t *testing.T) {
+	name := gava.String("alice")
+	inner := annotated("com.acme.Inner", 0x02)
+	outer := &gava.ClassDetails{
+		ClassName:        "com.acme.Outer",
+		SerialVersionUID: 1,
+		ClassDescFlags:   0x02,
+		FieldDescription: []*gava.ClassField{
+			{TypeCode: 'L', Name: "inner", Data: inner},
+			{TypeCode: 'L', Name: "name", Data: name},
+			{TypeCode: '[', Name: "names", Data: &gava.JavaArray{ClassName: "[Ljava.lang.String;", Elements: []interface{}{name, nil}}},
+		},
+		SuperClass: annotated("com.acme.Base", 0x03, gava.BlockData{1, 2}, inner),
+	}
+	var buf bytes.Buffer
+	e := gava.NewEncoder(&buf)
+	assert.NoError(t, e.Encode(outer))
+	assert.NoError(t, e.Reset())
+	assert.NoError(t, e.Encode(gava.String("after")))
+	data := buf.Bytes()
+
+	r := &recorder{}
+	assert.NoError(t, gava.Visit(data, r))
+	assert.Equal(t, []string{
+		"object com.acme.Outer 7e0005",
+		"field com.acme.Base.id", "value 7",
+		"block 01 02",
+		"object com.acme.Inner 7e0007", "field com.acme.Inner.id", "value 7", "end",
+		"field com.acme.Outer.inner", "reference 7e0007",
+		"field com.acme.Outer.name", "value alice",
+		"field com.acme.Outer.names", "array [Ljava.lang.String; 2", "reference 7e0008", "value <nil>", "end",
+		"end",
+		"reset",
+		"value after",
+	}, r.events)
+
+	//Skipped values are read without their events
+	r = &recorder{skip: map[string]bool{"id": true, "[Ljava.lang.String;": true}}
+	assert.NoError(t, gava.Visit(data, r))
+	assert.Equal(t, []string{
+		"object com.acme.Outer 7e0005",
+		"field com.acme.Base.id",
+		"block 01 02",
+		"object com.acme.Inner 7e0007", "field com.acme.Inner.id", "end",
+		"field com.acme.Outer.inner", "reference 7e0007",
+		"field com.acme.Outer.name", "value alice",
+		"field com.acme.Outer.names", "array [Ljava.lang.String; 2",
+		"end",
+		"reset",
+		"value after",
+	}, r.events)
+
+	r = &recorder{stop: "com.acme.Inner"}
+	assert.NoError(t, gava.Visit(data, r))
+	assert.Equal(t, "object com.acme.Inner 7e0007", r.events[len(r.events)-1])
+
+	//Errors are reported like decoding
+	assert.Error(t, gava.Visit(data[:len(data)-3], &recorder{}))
+	assert.ErrorIs(t, gava.DecoderOptions{MaxObjects: 2}.Visit(data, &recorder{}), gava.ErrMaxObjects)
+}
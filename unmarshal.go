@@ -0,0 +1,432 @@
+package gava
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Unmarshal decodes the first object of the serialization stream in data and stores it
+// in the value pointed to by v.
+//
+// Java fields are stored in the exported struct field tagged `java:"name"`, or else in the
+// one whose name matches the Java field name case-insensitively. An embedded struct
+// without a tag receives the fields of the super class, when there is none the super
+// class fields are matched against the struct itself. Collections, maps and other values
+// decoded by a ClassReader are converted into slices, maps and the Go types of the fields,
+// and null leaves the zero value. Java fields without a Go field are ignored. Go types
+// implementing Unmarshaler or StreamUnmarshaler decode their objects themselves.
+func Unmarshal(data []byte, v interface{}) error {
+	return UnmarshalOptions{}.Unmarshal(data, v)
+}
+
+// UnmarshalOptions controls how Java data that doesn't fit the Go value is handled.
+type UnmarshalOptions struct {
+	// DisallowUnknownFields makes Java fields without a Go field an error.
+	DisallowUnknownFields bool
+	// IgnoreTypeMismatch leaves Go values that can't hold the Java value untouched
+	// instead of returning an UnmarshalTypeError.
+	IgnoreTypeMismatch bool
+	// Decoder limits the resources decoding the stream may use.
+	Decoder DecoderOptions
+}
+
+// Unmarshal is like the package level Unmarshal with the options applied.
+func (o UnmarshalOptions) Unmarshal(data []byte, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return &InvalidUnmarshalError{Type: reflect.TypeOf(v)}
+	}
+	content, err := o.Decoder.NewGavaDeserilizer(data).decodeContent()
+	if err != nil {
+		return err
+	}
+	d := &unmarshalState{opts: o, objects: map[objectKey]reflect.Value{}}
+	return d.value(content, rv.Elem(), "")
+}
+
+// Unmarshaler is implemented by Go types that decode the Java object they are unmarshalled
+// from themselves, the object is passed with its fields read and its super class parts linked.
+type Unmarshaler interface {
+	UnmarshalJava(obj *ClassDetails) error
+}
+
+// StreamUnmarshaler is implemented by Go types that read the data the Java class wrote from
+// its writeObject or writeExternal method, the same way the class reads it back.
+type StreamUnmarshaler interface {
+	UnmarshalJavaStream(in *ObjectInput) error
+}
+
+// InvalidUnmarshalError is returned when Unmarshal is given a nil or non-pointer value.
+type InvalidUnmarshalError struct {
+	Type reflect.Type
+}
+
+func (e *InvalidUnmarshalError) Error() string {
+	if e.Type == nil {
+		return "gava: Unmarshal(nil)"
+	}
+	if e.Type.Kind() != reflect.Ptr {
+		return "gava: Unmarshal(non-pointer " + e.Type.String() + ")"
+	}
+	return "gava: Unmarshal(nil " + e.Type.String() + ")"
+}
+
+// UnmarshalTypeError describes a Java value that can't be stored in a Go value.
+type UnmarshalTypeError struct {
+	Value string
+	Type  reflect.Type
+	Field string
+}
+
+func (e *UnmarshalTypeError) Error() string {
+	if e.Field != "" {
+		return "gava: cannot unmarshal " + e.Value + " into Go struct field " + e.Field + " of type " + e.Type.String()
+	}
+	return "gava: cannot unmarshal " + e.Value + " into Go value of type " + e.Type.String()
+}
+
+// UnknownFieldError is returned for a Java field without a Go field when unknown fields are disallowed.
+type UnknownFieldError struct {
+	ClassName string
+	Field     string
+}
+
+func (e *UnknownFieldError) Error() string {
+	return "gava: unknown field " + e.Field + " of class " + e.ClassName
+}
+
+type objectKey struct {
+	obj *ClassDetails
+	typ reflect.Type
+}
+
+type unmarshalState struct {
+	opts UnmarshalOptions
+	// objects keeps the Go pointer made for each object so shared and cyclic references stay shared
+	objects map[objectKey]reflect.Value
+}
+
+func (d *unmarshalState) value(v interface{}, rv reflect.Value, path string) error {
+	if v == nil {
+		rv.Set(reflect.Zero(rv.Type()))
+		return nil
+	}
+	if obj, ok := v.(*ClassDetails); ok && rv.CanAddr() {
+		switch u := rv.Addr().Interface().(type) {
+		case Unmarshaler:
+			return u.UnmarshalJava(obj)
+		case StreamUnmarshaler:
+			return u.UnmarshalJavaStream(NewObjectInput(writerPart(obj)))
+		}
+	}
+	//Empty interfaces get the decoded value, the way map and slice elements do
+	if rv.Kind() == reflect.Interface && rv.NumMethod() == 0 {
+		if dv := decodedValue(v); dv != nil {
+			rv.Set(reflect.ValueOf(dv))
+		} else {
+			rv.Set(reflect.Zero(rv.Type()))
+		}
+		return nil
+	}
+	if d.assign(v, rv) {
+		return nil
+	}
+	if o, ok := decodedValue(v).(Option); ok {
+		if !o.Defined {
+			rv.Set(reflect.Zero(rv.Type()))
+			return nil
+		}
+		return d.value(o.Value, rv, path)
+	}
+
+	switch rv.Kind() {
+	case reflect.Ptr:
+		if obj, ok := v.(*ClassDetails); ok {
+			key := objectKey{obj, rv.Type()}
+			if p, ok := d.objects[key]; ok {
+				rv.Set(p)
+				return nil
+			}
+			p := reflect.New(rv.Type().Elem())
+			d.objects[key] = p
+			rv.Set(p)
+			return d.value(v, p.Elem(), path)
+		}
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		return d.value(v, rv.Elem(), path)
+	case reflect.Struct:
+		if obj, ok := v.(*ClassDetails); ok {
+			return d.object(obj, rv, path)
+		}
+	}
+	return d.native(v, rv, path)
+}
+
+// assign stores values that already have the Go type, such as a *ClassDetails or the
+// net.IP of an InetAddress.
+func (d *unmarshalState) assign(v interface{}, rv reflect.Value) bool {
+	for _, c := range []interface{}{v, decodedValue(v)} {
+		cv := reflect.ValueOf(c)
+		if cv.Type().AssignableTo(rv.Type()) {
+			rv.Set(cv)
+			return true
+		}
+		if cv.Kind() == reflect.Ptr && !cv.IsNil() && cv.Elem().Type().AssignableTo(rv.Type()) {
+			rv.Set(cv.Elem())
+			return true
+		}
+	}
+	return false
+}
+
+// native converts the decoded Go value of v into the kind of rv.
+func (d *unmarshalState) native(v interface{}, rv reflect.Value, path string) error {
+	nv := decodedValue(v)
+	switch rv.Kind() {
+	case reflect.Bool:
+		if b, ok := nv.(bool); ok {
+			rv.SetBool(b)
+			return nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if i, ok := integer(nv); ok && !rv.OverflowInt(i) {
+			rv.SetInt(i)
+			return nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if i, ok := integer(nv); ok && i >= 0 && !rv.OverflowUint(uint64(i)) {
+			rv.SetUint(uint64(i))
+			return nil
+		}
+	case reflect.Float32, reflect.Float64:
+		switch f := nv.(type) {
+		case float32:
+			rv.SetFloat(float64(f))
+			return nil
+		case float64:
+			rv.SetFloat(f)
+			return nil
+		}
+		if i, ok := integer(nv); ok {
+			rv.SetFloat(float64(i))
+			return nil
+		}
+	case reflect.String:
+		switch s := nv.(type) {
+		case string:
+			rv.SetString(s)
+			return nil
+		case uint16:
+			rv.SetString(string(rune(s)))
+			return nil
+		case *JavaEnum:
+			rv.SetString(s.Constant)
+			return nil
+		case fmt.Stringer:
+			rv.SetString(s.String())
+			return nil
+		}
+	case reflect.Slice:
+		if b, ok := nv.([]byte); ok && rv.Type().Elem().Kind() == reflect.Uint8 {
+			rv.SetBytes(append([]byte{}, b...))
+			return nil
+		}
+		if s, ok := nv.([]interface{}); ok {
+			slice := reflect.MakeSlice(rv.Type(), len(s), len(s))
+			for i, e := range s {
+				if err := d.value(e, slice.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+			rv.Set(slice)
+			return nil
+		}
+	case reflect.Array:
+		if s, ok := nv.([]interface{}); ok && len(s) == rv.Len() {
+			for i, e := range s {
+				if err := d.value(e, rv.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	case reflect.Map:
+		if m, ok := nv.(map[interface{}]interface{}); ok {
+			t := rv.Type()
+			result := reflect.MakeMapWithSize(t, len(m))
+			for k, e := range m {
+				key := reflect.New(t.Key()).Elem()
+				if err := d.value(k, key, path); err != nil {
+					return err
+				}
+				elem := reflect.New(t.Elem()).Elem()
+				if err := d.value(e, elem, fmt.Sprintf("%s[%v]", path, k)); err != nil {
+					return err
+				}
+				result.SetMapIndex(key, elem)
+			}
+			rv.Set(result)
+			return nil
+		}
+	}
+	if d.opts.IgnoreTypeMismatch {
+		return nil
+	}
+	return &UnmarshalTypeError{Value: javaTypeName(v), Type: rv.Type(), Field: path}
+}
+
+// object stores the fields of obj in the struct rv, handing the super class fields to
+// the embedded struct when there is one.
+func (d *unmarshalState) object(obj *ClassDetails, rv reflect.Value, path string) error {
+	if path == "" {
+		path = rv.Type().Name()
+	}
+	fields := structFields(rv.Type())
+	for part := obj; part != nil; part = part.SuperClass {
+		for _, f := range part.FieldDescription {
+			i, ok := fields.lookup(f.Name)
+			if !ok {
+				if d.opts.DisallowUnknownFields {
+					return &UnknownFieldError{ClassName: part.ClassName, Field: f.Name}
+				}
+				continue
+			}
+			if err := d.value(f.Data, rv.Field(i), path+"."+rv.Type().Field(i).Name); err != nil {
+				return err
+			}
+		}
+		if fields.super >= 0 && part.SuperClass != nil {
+			sv := rv.Field(fields.super)
+			if sv.Kind() == reflect.Ptr {
+				if sv.IsNil() {
+					sv.Set(reflect.New(sv.Type().Elem()))
+				}
+				sv = sv.Elem()
+			}
+			return d.object(part.SuperClass, sv, path)
+		}
+	}
+	return nil
+}
+
+// writerPart returns the most derived part of obj whose class wrote data of its own,
+// or obj when none did.
+func writerPart(obj *ClassDetails) *ClassDetails {
+	for part := obj; part != nil; part = part.SuperClass {
+		if part.ClassDescFlags&(0x01|0x04) != 0 {
+			return part
+		}
+	}
+	return obj
+}
+
+type goField struct {
+	name   string
+	index  int
+	tagged bool
+}
+
+type goFields struct {
+	fields []goField
+	// super is the index of the embedded struct that holds the super class, or -1
+	super int
+}
+
+func structFields(t reflect.Type) goFields {
+	fields := goFields{super: -1}
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("java")
+		if tag == "-" || (sf.PkgPath != "" && !embeddedStruct(sf)) {
+			continue
+		}
+		ft := sf.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if sf.Anonymous && tag == "" && ft.Kind() == reflect.Struct && fields.super < 0 {
+			fields.super = i
+			continue
+		}
+		if tag != "" {
+			fields.fields = append(fields.fields, goField{name: tag, index: i, tagged: true})
+		} else {
+			fields.fields = append(fields.fields, goField{name: sf.Name, index: i})
+		}
+	}
+	return fields
+}
+
+// embeddedStruct reports whether sf embeds a struct by value, whose exported fields can
+// be set even when the struct type is unexported.
+func embeddedStruct(sf reflect.StructField) bool {
+	return sf.Anonymous && sf.Type.Kind() == reflect.Struct
+}
+
+// lookup returns the Go field for a Java field, an exact match wins over a case-insensitive one.
+func (f goFields) lookup(name string) (int, bool) {
+	for _, gf := range f.fields {
+		if gf.name == name {
+			return gf.index, true
+		}
+	}
+	for _, gf := range f.fields {
+		if !gf.tagged && strings.EqualFold(gf.name, name) {
+			return gf.index, true
+		}
+	}
+	return 0, false
+}
+
+func integer(v interface{}) (int64, bool) {
+	switch i := v.(type) {
+	case int8:
+		return int64(i), true
+	case uint16:
+		return int64(i), true
+	case int16:
+		return int64(i), true
+	case int32:
+		return int64(i), true
+	case int64:
+		return i, true
+	}
+	return 0, false
+}
+
+// javaTypeName describes the Java type of a value for error messages.
+func javaTypeName(v interface{}) string {
+	switch v := v.(type) {
+	case bool:
+		return "boolean"
+	case int8:
+		return "byte"
+	case uint16:
+		return "char"
+	case int16:
+		return "short"
+	case int32:
+		return "int"
+	case int64:
+		return "long"
+	case float32:
+		return "float"
+	case float64:
+		return "double"
+	case *JavaString, string:
+		return "String"
+	case *JavaArray:
+		return "array " + v.ClassName
+	case *JavaEnum:
+		return "enum " + v.ClassName
+	case *ClassDetails:
+		if v == nil {
+			return "null"
+		}
+		return "object " + v.ClassName
+	}
+	return fmt.Sprintf("%T", v)
+}
@@ -0,0 +1,271 @@
+package gava
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Violation is a place where a stream departs from the Object Serialization Stream Protocol,
+// or from the way ObjectOutputStream writes it.
+type Violation struct {
+	Offset  int
+	Message string
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("offset %d: %s", v.Offset, v.Message)
+}
+
+// Validate checks the stream in data against the grammar of the Object Serialization Stream
+// Protocol, and returns the violations found ordered by offset, or nil when there are none.
+// Besides the problems decoding reports, it checks:
+//
+//   - class descriptor flags, names and field type signatures
+//   - that primitive fields come before object fields, each sorted by name
+//   - enum and array class descriptors, and that array descriptor names match the elements
+//   - that field values are of the kind of their field: arrays for array fields, strings for
+//     String fields, and values a field of its type can hold for references
+//   - block data and long strings written the way ObjectOutputStream does: blocks of at most
+//     1024 bytes, TC_BLOCKDATALONG and TC_LONGSTRING only for lengths that need them
+//
+// Damaged streams are parsed in lenient mode, every problem found is reported.
+func Validate(data []byte) []Violation {
+	g := DecoderOptions{Lenient: true}.NewGavaDeserilizer(data)
+	g.validating = true
+	doc, err := g.decodeDocument()
+	if err != nil {
+		pe := err.(*ParseError)
+		return []Violation{{Offset: pe.Offset, Message: pe.Err.Error()}}
+	}
+
+	v := &validator{}
+	for _, d := range doc.Diagnostics {
+		v.add(d.Offset, d.Err.Error())
+	}
+	w := &walker{object: v.object, desc: v.desc, array: v.array, enum: v.enum}
+	w.walk(doc.Contents)
+	sort.SliceStable(v.violations, func(i, j int) bool { return v.violations[i].Offset < v.violations[j].Offset })
+	return v.violations
+}
+
+// nonConforming records what ObjectOutputStream doesn't write, when validating.
+func (g *GavaDeserilizer) nonConforming(offset int, msg string) {
+	if g.validating {
+		g.diagnostics = append(g.diagnostics, Diagnostic{Offset: offset, Severity: DiagnosticWarning, Err: fmt.Errorf("%s", msg)})
+	}
+}
+
+type validator struct {
+	violations []Violation
+}
+
+func (v *validator) add(offset int, format string, args ...interface{}) {
+	v.violations = append(v.violations, Violation{Offset: offset, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) desc(desc *ClassDetails) {
+	offset := desc.Span.Offset
+	if desc.ProxyInterfaces != nil {
+		for _, name := range desc.ProxyInterfaces {
+			if !validSignature("L"+name+";", '.') {
+				v.add(offset, "invalid proxy interface name %q", name)
+			}
+		}
+		return
+	}
+	if strings.HasPrefix(desc.ClassName, "[") {
+		if !validSignature(desc.ClassName, '.') {
+			v.add(offset, "invalid array class name %q", desc.ClassName)
+		}
+		if len(desc.FieldDescription) > 0 {
+			v.add(offset, "array class %s has fields", desc.ClassName)
+		}
+	} else if !validSignature("L"+desc.ClassName+";", '.') {
+		v.add(offset, "invalid class name %q", desc.ClassName)
+	}
+	if desc.ClassDescFlags&0x10 == 0x10 { //SC_ENUM
+		if desc.ClassDescFlags&0x02 == 0 {
+			v.add(offset, "enum class %s is not SC_SERIALIZABLE", desc.ClassName)
+		}
+		if desc.SerialVersionUID != 0 {
+			v.add(offset, "enum class %s has serialVersionUID %d, should be 0", desc.ClassName, desc.SerialVersionUID)
+		}
+		if len(desc.FieldDescription) > 0 {
+			v.add(offset, "enum class %s has fields", desc.ClassName)
+		}
+	}
+
+	names := map[string]bool{}
+	var last *ClassField
+	for _, f := range desc.FieldDescription {
+		if names[f.Name] {
+			v.add(offset, "duplicate field %s.%s", desc.ClassName, f.Name)
+		}
+		names[f.Name] = true
+		switch f.TypeCode {
+		case 'L':
+			if !strings.HasPrefix(f.className, "L") || !validSignature(f.className, '/') {
+				v.add(offset, "invalid type %q of object field %s.%s", f.className, desc.ClassName, f.Name)
+			}
+		case '[':
+			if !strings.HasPrefix(f.className, "[") || !validSignature(f.className, '/') {
+				v.add(offset, "invalid type %q of array field %s.%s", f.className, desc.ClassName, f.Name)
+			}
+		}
+		if last != nil && !fieldOrder(last, f) {
+			v.add(offset, "field %s.%s is out of order, primitive fields come first and each kind is sorted by name", desc.ClassName, f.Name)
+		} else {
+			last = f
+		}
+	}
+}
+
+func (v *validator) enum(e *JavaEnum) {
+	if e.desc == nil {
+		return
+	}
+	descs := e.desc.ClassDetail
+	for _, desc := range descs {
+		if desc.ClassDescFlags&0x10 == 0 {
+			v.add(e.Span.Offset, "enum class %s is not SC_ENUM", desc.ClassName)
+		}
+	}
+	if last := descs[len(descs)-1]; last.ClassName != "java.lang.Enum" {
+		v.add(e.Span.Offset, "enum class %s doesn't extend java.lang.Enum", descs[0].ClassName)
+	}
+}
+
+func (v *validator) array(a *JavaArray) {
+	if a.desc != nil && a.desc.ClassDetail[0].ClassDescFlags != 0x02 {
+		v.add(a.Span.Offset, "array class %s has flags 0x%02x, should be SC_SERIALIZABLE", a.ClassName, a.desc.ClassDetail[0].ClassDescFlags)
+	}
+	component := a.ClassName[1:]
+	for i, e := range a.Elements {
+		if len(component) > 0 && (component[0] == 'L' || component[0] == '[') && !assignable(strings.Replace(component, ".", "/", -1), e) {
+			v.add(a.Span.Offset, "element %d of %s is %s", i, a.ClassName, kind(e))
+		}
+	}
+}
+
+func (v *validator) object(obj *ClassDetails) {
+	offset := obj.Span.Offset
+	if obj.desc == nil && obj.ProxyInterfaces == nil {
+		//A reference to a class descriptor handle
+		return
+	}
+	for part := obj; part != nil; part = part.SuperClass {
+		for _, f := range part.FieldDescription {
+			if _, ok := f.Data.(*JavaArray); f.TypeCode == '[' && f.Data != nil && !ok {
+				v.add(offset, "array field %s.%s holds %s", part.ClassName, f.Name, kind(f.Data))
+			} else if (f.TypeCode == 'L' || f.TypeCode == '[') && f.className != "" && !assignable(f.className, f.Data) {
+				v.add(offset, "field %s.%s of type %s holds %s", part.ClassName, f.Name, f.className, kind(f.Data))
+			}
+		}
+		for _, a := range part.Annotation {
+			if b, ok := a.(BlockData); ok && len(b) > 1024 {
+				v.add(offset, "block data of %d bytes in %s, ObjectOutputStream writes blocks of at most 1024 bytes", len(b), part.ClassName)
+			}
+		}
+	}
+}
+
+// fieldOrder reports whether Java sorts field a before field b: primitive fields first, then
+// by name.
+func fieldOrder(a, b *ClassField) bool {
+	aPrimitive, bPrimitive := a.TypeCode != 'L' && a.TypeCode != '[', b.TypeCode != 'L' && b.TypeCode != '['
+	if aPrimitive != bPrimitive {
+		return aPrimitive
+	}
+	return a.Name < b.Name
+}
+
+// validSignature reports whether s is a type signature such as "I", "Ljava/lang/String;" or
+// "[[J", with sep separating the parts of class names.
+func validSignature(s string, sep byte) bool {
+	s = strings.TrimLeft(s, "[")
+	if len(s) == 1 {
+		return strings.IndexByte("BCDFIJSZ", s[0]) >= 0
+	}
+	if len(s) < 3 || s[0] != 'L' || s[len(s)-1] != ';' {
+		return false
+	}
+	for _, part := range strings.Split(s[1:len(s)-1], string(sep)) {
+		if part == "" || strings.ContainsAny(part, "./;[") {
+			return false
+		}
+	}
+	return true
+}
+
+// assignable reports whether a field or array element of the type signature can hold the
+// value. Object classes aren't checked, their hierarchy isn't in the stream.
+func assignable(signature string, value interface{}) bool {
+	any := signature == "Ljava/lang/Object;" || signature == "Ljava/io/Serializable;"
+	switch value := value.(type) {
+	case nil:
+		return true
+	case *JavaString:
+		return any || signature == "Ljava/lang/String;" || signature == "Ljava/lang/CharSequence;" || signature == "Ljava/lang/Comparable;"
+	case *JavaArray:
+		return assignableType(signature, strings.Replace(value.ClassName, ".", "/", -1))
+	case *ClassDataDesc:
+		return any || signature == "Ljava/lang/Class;" || signature == "Ljava/lang/reflect/Type;"
+	case *ClassDetails:
+		if value == nil {
+			return true
+		}
+		if value.desc == nil && value.ProxyInterfaces == nil {
+			return any || signature == "Ljava/io/ObjectStreamClass;"
+		}
+		return assignableObject(signature)
+	case *JavaEnum:
+		return assignableObject(signature)
+	}
+	//Primitive values don't appear where references do
+	return false
+}
+
+// assignableObject reports whether a field or array element of the type signature can hold
+// an object or enum, whose class may implement any interface and extend any class Java
+// doesn't make final.
+func assignableObject(signature string) bool {
+	return !strings.HasPrefix(signature, "[") && signature != "Ljava/lang/String;" && signature != "Ljava/lang/Class;"
+}
+
+// assignableType reports whether a value of the type signature source can be assigned to the
+// type target, the classes of objects are taken to be assignable.
+func assignableType(target, source string) bool {
+	primitive := len(source) == 1
+	switch {
+	case target == source:
+		return true
+	case target == "Ljava/lang/Object;" || target == "Ljava/io/Serializable;":
+		return !primitive
+	case target == "Ljava/lang/Cloneable;":
+		return source[0] == '['
+	case target[0] == '[' && source[0] == '[':
+		return assignableType(target[1:], source[1:])
+	}
+	return target[0] == 'L' && source[0] == 'L'
+}
+
+// kind describes a value in violations.
+func kind(value interface{}) string {
+	switch value := value.(type) {
+	case *JavaString:
+		return "a string"
+	case *JavaArray:
+		return "an array " + value.ClassName
+	case *ClassDataDesc:
+		return "a class"
+	case *ClassDetails:
+		if value.desc == nil && value.ProxyInterfaces == nil {
+			return "a class descriptor"
+		}
+		return "an object " + partLabel(value)
+	case *JavaEnum:
+		return "an enum " + value.ClassName
+	}
+	return fmt.Sprintf("%T", value)
+}
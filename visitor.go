@@ -0,0 +1,128 @@
+package gava
+
+// Action tells the parser how to go on after a Visitor event.
+type Action int
+
+const (
+	// Continue sends the events of the value.
+	Continue Action = iota
+	// Skip reads the value without sending its events.
+	Skip
+	// Stop ends the traversal, Visit returns nil.
+	Stop
+)
+
+// Visitor receives the events of a stream from Visit, in stream order, without the values
+// being built into a tree. Events inside a value skipped with Skip aren't sent. Embed
+// BaseVisitor to implement only the events needed.
+type Visitor interface {
+	// StartObject is called when an object starts, with its classes and handle but without
+	// field values. The values of its fields follow, from the top super class down, each
+	// after a Field event, with the annotations written by the class after its fields.
+	StartObject(obj *ClassDetails) Action
+	EndObject()
+	// Field is called before the value of a field of the part of an object.
+	Field(part *ClassDetails, field *ClassField) Action
+	// StartArray is called when an array starts, its elements follow.
+	StartArray(className string, length int, handle int) Action
+	EndArray()
+	// Value is called for primitive values, strings, enums, classes and nulls.
+	Value(value interface{})
+	BlockData(data BlockData)
+	// Reference is called for a reference to a value sent before, by its handle.
+	Reference(handle int)
+	Reset()
+	// Exception is called before the object of a TC_EXCEPTION.
+	Exception()
+}
+
+// BaseVisitor is a Visitor that continues at every event.
+type BaseVisitor struct{}
+
+func (BaseVisitor) StartObject(obj *ClassDetails) Action                   { return Continue }
+func (BaseVisitor) EndObject()                                             {}
+func (BaseVisitor) Field(part *ClassDetails, field *ClassField) Action     { return Continue }
+func (BaseVisitor) StartArray(className string, length, handle int) Action { return Continue }
+func (BaseVisitor) EndArray()                                              {}
+func (BaseVisitor) Value(value interface{})                                {}
+func (BaseVisitor) BlockData(data BlockData)                               {}
+func (BaseVisitor) Reference(handle int)                                   {}
+func (BaseVisitor) Reset()                                                 {}
+func (BaseVisitor) Exception()                                             {}
+
+// Visit sends the events of the stream in data to v, see Visitor.
+func Visit(data []byte, v Visitor) error {
+	return DecoderOptions{}.Visit(data, v)
+}
+
+// Visit sends the events of the stream in data to v, within the limits of the options. Only
+// what is needed to follow references is kept: class descriptors, strings, and objects without
+// their field values.
+func (o DecoderOptions) Visit(data []byte, v Visitor) (err error) {
+	g := o.NewGavaDeserilizer(data)
+	g.visitor = v
+	defer func() {
+		if r := recover(); r != nil && r != (stopVisit{}) {
+			err = g.parseError(r)
+		}
+	}()
+	g.readHeader()
+	for len(g.data) > 0 {
+		g.readContentElement()
+	}
+	return nil
+}
+
+type stopVisit struct{}
+
+type visitState byte
+
+const (
+	notVisited visitState = iota
+	visited
+	skipped
+)
+
+// startVisit sends the event starting a value, and skips the events inside it when the
+// visitor says so or when the value is inside a skipped one.
+func (g *GavaDeserilizer) startVisit(start func(Visitor) Action) visitState {
+	if g.visitor == nil {
+		return notVisited
+	}
+	if g.skipping == 0 {
+		switch start(g.visitor) {
+		case Continue:
+			return visited
+		case Stop:
+			panic(stopVisit{})
+		}
+	}
+	g.skipping++
+	return skipped
+}
+
+// endVisit ends a value started with startVisit, sending end when it isn't nil.
+func (g *GavaDeserilizer) endVisit(s visitState, end func(Visitor)) {
+	switch s {
+	case visited:
+		if end != nil {
+			end(g.visitor)
+		}
+	case skipped:
+		g.skipping--
+	}
+}
+
+// event sends an event outside of skipped values.
+func (g *GavaDeserilizer) event(send func(Visitor)) {
+	if g.visitor != nil && g.skipping == 0 {
+		send(g.visitor)
+	}
+}
+
+// value sends a Value event.
+func (g *GavaDeserilizer) value(v interface{}) {
+	if g.visitor != nil && g.skipping == 0 {
+		g.visitor.Value(v)
+	}
+}
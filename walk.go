@@ -0,0 +1,111 @@
+package gava
+
+// walker visits every node reachable from the contents of a stream once: objects with
+// all their parts, class descriptors with their super classes and annotations, arrays,
+// enums and strings. Callbacks left nil are skipped.
+type walker struct {
+	object func(obj *ClassDetails)
+	desc   func(desc *ClassDetails)
+	array  func(array *JavaArray)
+	enum   func(enum *JavaEnum)
+	str    func(s *JavaString)
+	seen   map[interface{}]bool
+}
+
+func (w *walker) walk(contents []interface{}) {
+	if w.seen == nil {
+		w.seen = map[interface{}]bool{}
+	}
+	for _, c := range contents {
+		w.content(c)
+	}
+}
+
+func (w *walker) content(v interface{}) {
+	switch v := v.(type) {
+	case *ClassDetails:
+		if v == nil || w.seen[v] {
+			return
+		}
+		w.seen[v] = true
+		if w.object != nil {
+			w.object(v)
+		}
+		for part := v; part != nil; part = part.SuperClass {
+			if part.desc != nil {
+				w.descriptor(part.desc)
+			}
+			for _, f := range part.FieldDescription {
+				w.content(f.Data)
+			}
+			for _, a := range part.Annotation {
+				w.content(a)
+			}
+		}
+	case *JavaArray:
+		if v == nil || w.seen[v] {
+			return
+		}
+		w.seen[v] = true
+		if w.array != nil {
+			w.array(v)
+		}
+		if v.desc != nil {
+			w.descriptors(v.desc)
+		}
+		for _, e := range v.Elements {
+			w.content(e)
+		}
+	case *JavaEnum:
+		if v == nil || w.seen[v] {
+			return
+		}
+		w.seen[v] = true
+		if w.enum != nil {
+			w.enum(v)
+		}
+		if v.desc != nil {
+			w.descriptors(v.desc)
+		}
+		if v.constant != nil {
+			w.content(v.constant)
+		}
+	case *ClassDataDesc:
+		if v == nil {
+			return
+		}
+		w.descriptors(v)
+	case *JavaString:
+		if v == nil || w.seen[v] {
+			return
+		}
+		w.seen[v] = true
+		if w.str != nil {
+			w.str(v)
+		}
+	}
+}
+
+func (w *walker) descriptors(cdd *ClassDataDesc) {
+	for _, desc := range cdd.ClassDetail {
+		w.descriptor(desc)
+	}
+}
+
+func (w *walker) descriptor(desc *ClassDetails) {
+	if w.seen[desc] {
+		return
+	}
+	w.seen[desc] = true
+	if w.desc != nil {
+		w.desc(desc)
+	}
+	for _, f := range desc.FieldDescription {
+		if f.typeString != nil {
+			w.content(f.typeString)
+		}
+	}
+	for _, a := range desc.Annotation {
+		w.content(a)
+	}
+}